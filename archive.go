@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+const (
+	fetchLive   = "live"
+	fetchRecord = "record" // fetch live and save every response to the archive
+	fetchReplay = "replay" // serve every response from the archive, never touching the network
+)
+
+const archiveTruncated = "X-Archive-Truncated" // set on recordings whose body went past maxBytes
+
+var errNotArchived = errors.New("response not in archive")
+
+type recordingFetcher struct {
+	next     fetcher
+	dir      string
+	maxBytes int64 // the crawler never reads further, 0 records whole bodies
+}
+
+type replayFetcher struct {
+	dir string
+}
+
+func archivePath(dir string, req *http.Request) string { // one file per method, url and validators, the latest recording wins
+	key := req.Method + " " + req.URL.String()
+	for _, name := range []string{"If-None-Match", "If-Modified-Since"} { // a conditional re-crawl replays its 304 without clobbering the 200
+		if value := req.Header.Get(name); value != "" {
+			key += "\n" + name + ": " + value
+		}
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".http")
+}
+
+func (f *recordingFetcher) do(req *http.Request) (*http.Response, error) {
+	res, err := f.next.do(req)
+	if err != nil {
+		return nil, err
+	}
+	reader := io.Reader(res.Body)
+	if f.maxBytes > 0 {
+		reader = io.LimitReader(res.Body, f.maxBytes+1) // the extra byte keeps the body over the limit on replay
+	}
+	body, err := io.ReadAll(reader)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	saved := *res // stored as an http/1.1 message with a known length, whatever came over the wire
+	if f.maxBytes > 0 && int64(len(body)) > f.maxBytes {
+		saved.Header = res.Header.Clone()
+		saved.Header.Set(archiveTruncated, "true")
+	}
+	saved.ProtoMajor, saved.ProtoMinor = 1, 1
+	saved.TransferEncoding = nil
+	saved.ContentLength = int64(len(body))
+	saved.Body = io.NopCloser(bytes.NewReader(body))
+	buf := &bytes.Buffer{}
+	if err := saved.Write(buf); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(archivePath(f.dir, req), buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
+
+func (f *replayFetcher) do(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	raw, err := os.ReadFile(archivePath(f.dir, req))
+	if errors.Is(err, fs.ErrNotExist) && (req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "") {
+		plain := req.Clone(req.Context()) // validators the recording never sent, answer as a full fetch would
+		plain.Header.Del("If-None-Match")
+		plain.Header.Del("If-Modified-Since")
+		raw, err = os.ReadFile(archivePath(f.dir, plain))
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errNotArchived
+	} else if err != nil {
+		return nil, err
+	}
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(raw)), req)
+}
+
+func writeFileAtomic(path string, data []byte) error { // concurrent workers never see half a file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
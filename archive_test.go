@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func (f *fakeDB) stored() map[string]fakeRow { // stored url to every column written
+	res := make(map[string]fakeRow)
+	for _, row := range f.written("INSERT INTO", "data") {
+		res[row["url"].(string)] = row
+	}
+	return res
+}
+
+func TestRecordReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Header().Set("ETag", `"home"`)
+			fmt.Fprint(w, `<p>home page</p><a href="/old">old</a><a href="/latin">latin</a>`)
+		case "/old":
+			http.Redirect(w, req, "/new", http.StatusMovedPermanently)
+		case "/new":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p>new page</p>`)
+		case "/latin":
+			w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+			fmt.Fprint(w, "<p>caf\xe9 cr\xe8me</p>")
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	dir := t.TempDir()
+	opts := crawlOptions{MaxPages: 10, MaxDepth: intPtr(2), Concurrency: 1}
+
+	recordDB := &fakeDB{mu: &sync.Mutex{}}
+	recorder := testCrawler(t, recordDB, server.URL, opts)
+	recorder.fetcher = &recordingFetcher{
+		next: recorder.fetcher,
+		dir:  dir,
+	}
+	recorder.robots.fetcher = recorder.fetcher
+	recorder.initCrawl(context.Background(), server.URL)
+	server.Close() // replay must not need the network
+
+	replayDB := &fakeDB{mu: &sync.Mutex{}}
+	replayer := testCrawler(t, replayDB, server.URL, opts)
+	replayer.fetcher = &replayFetcher{dir: dir}
+	replayer.robots.fetcher = replayer.fetcher
+	replayer.initCrawl(context.Background(), server.URL)
+
+	expected := recordDB.stored()
+	if len(expected) != 3 {
+		t.Errorf("record failed, %v pages stored", len(expected))
+	}
+	if result := replayDB.stored(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("replay failed, %v != %v", result, expected)
+	}
+	if replayer.stats.errors != 0 {
+		t.Errorf("replay failed, %v errors: %v", replayer.stats.errors, replayer.stats.lastErr)
+	}
+}
+
+func TestRecordReplayExchanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("ETag", `"v1"`)
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, `<p>hello world</p>`)
+	}))
+	dir := t.TempDir()
+	recorder := &recordingFetcher{
+		next:     newHTTPFetcher(fetcherConfig{}),
+		dir:      dir,
+		maxBytes: 8,
+	}
+	replayer := &replayFetcher{dir: dir}
+
+	testCases := []struct {
+		name      string
+		etag      string
+		record    bool // only replayed otherwise, falling back to the unconditional recording
+		status    int
+		body      string
+		truncated string
+	}{
+		{
+			name:      "test case 1",
+			record:    true,
+			status:    http.StatusOK,
+			body:      "<p>hello ",
+			truncated: "true",
+		},
+		{
+			name:   "test case 2",
+			etag:   `"v1"`,
+			record: true,
+			status: http.StatusNotModified,
+		},
+		{
+			name:      "test case 3",
+			etag:      `"v0"`,
+			status:    http.StatusOK,
+			body:      "<p>hello ",
+			truncated: "true",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if testCase.etag != "" {
+				req.Header.Set("If-None-Match", testCase.etag)
+			}
+			if testCase.record {
+				res, err := recorder.do(req)
+				if err != nil {
+					t.Fatalf("%s failed, unexpected error: %v", testCase.name, err)
+				}
+				res.Body.Close()
+			}
+		})
+	}
+	server.Close()
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if testCase.etag != "" {
+				req.Header.Set("If-None-Match", testCase.etag)
+			}
+			res, err := replayer.do(req)
+			if err != nil {
+				t.Fatalf("%s failed, unexpected error: %v", testCase.name, err)
+			}
+			defer res.Body.Close()
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != testCase.status || string(body) != testCase.body || res.Header.Get(archiveTruncated) != testCase.truncated || res.Header.Get("ETag") != `"v1"` {
+				t.Errorf("%s failed, %v %q %q %q != %v %q %q %q", testCase.name, res.StatusCode, body, res.Header.Get(archiveTruncated), res.Header.Get("ETag"), testCase.status, testCase.body, testCase.truncated, `"v1"`)
+			}
+		})
+	}
+}
+
+func TestReplayMissing(t *testing.T) {
+	f := &replayFetcher{dir: t.TempDir()}
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.do(req); !errors.Is(err, errNotArchived) {
+		t.Errorf("replay failed, %v != %v", err, errNotArchived)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var hostProfile = idna.New( // idna.Lookup without the strict hostname rules, real hosts like my_site.example.com carry underscores
+	idna.MapForLookup(),
+	idna.BidiRule(),
+	idna.StrictDomainName(false),
+)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+type canonicalizer struct {
+	stripParams    []string // query parameters to drop, a trailing * matches by prefix
+	keepParams     []string // when set, only these query parameters survive
+	wwwEquivalent  bool     // treat www.example.com and example.com as one host
+	honorCanonical bool     // store pages under their <link rel="canonical"> when it stays on the host
+}
+
+var defaultCanonicalizer = canonicalizer{
+	stripParams:    []string{"utm_*", "gclid", "fbclid", "msclkid", "sessionid", "session_id", "phpsessid", "jsessionid", "sid"},
+	honorCanonical: true,
+}
+
+func (c canonicalizer) canonicalize(rawUrl string) (string, error) {
+	urlStruct, err := url.Parse(strings.TrimSpace(rawUrl))
+	if err != nil {
+		return "", err
+	}
+
+	scheme := strings.ToLower(urlStruct.Scheme)
+	if _, ok := defaultPorts[scheme]; !ok {
+		return "", errors.New("unsupported scheme")
+	}
+
+	host := strings.ToLower(urlStruct.Hostname())
+	if net.ParseIP(host) == nil {
+		host, err = hostProfile.ToASCII(host) // punycode, so both spellings of a host agree
+		if err != nil {
+			return "", err
+		}
+	}
+	if host == "" {
+		return "", errors.New("missing host")
+	}
+	if c.wwwEquivalent {
+		host = strings.TrimPrefix(host, "www.")
+	}
+	if strings.Contains(host, ":") { // ipv6 literal
+		host = "[" + host + "]"
+	}
+	if port := urlStruct.Port(); port != "" && port != defaultPorts[scheme] {
+		host += ":" + port
+	}
+
+	path := strings.TrimRight(removeDotSegments(urlStruct.EscapedPath()), "/")
+
+	res := scheme + "://" + host + path
+	if query := c.filterQuery(urlStruct.RawQuery); query != "" {
+		res += "?" + query
+	}
+	return res, nil // fragments never reach the server, so they are dropped
+}
+
+func removeDotSegments(path string) string { // rfc 3986 section 5.2.4
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	out := []string{}
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+			if last {
+				out = append(out, "")
+			}
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+			if last {
+				out = append(out, "")
+			}
+		default:
+			out = append(out, segment)
+		}
+	}
+	res := strings.Join(out, "/")
+	if strings.HasPrefix(path, "/") && !strings.HasPrefix(res, "/") {
+		res = "/" + res
+	}
+	return res
+}
+
+func (c canonicalizer) filterQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	type param struct {
+		key string
+		raw string
+	}
+	params := []param{}
+	for pair := range strings.SplitSeq(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		rawKey, _, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+		if !c.keepParam(strings.ToLower(key)) {
+			continue
+		}
+		params = append(params, param{
+			key: key,
+			raw: pair,
+		})
+	}
+
+	sort.SliceStable(params, func(i, j int) bool { // parameter order doesn't change the page
+		if params[i].key != params[j].key {
+			return params[i].key < params[j].key
+		}
+		return params[i].raw < params[j].raw
+	})
+
+	pairs := []string{}
+	for _, p := range params {
+		pairs = append(pairs, p.raw)
+	}
+	return strings.Join(pairs, "&")
+}
+
+func (c canonicalizer) keepParam(key string) bool {
+	if len(c.keepParams) != 0 {
+		return paramMatches(c.keepParams, key)
+	}
+	return !paramMatches(c.stripParams, key)
+}
+
+func paramMatches(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(key, prefix) {
+			return true
+		} else if pattern == key {
+			return true
+		}
+	}
+	return false
+}
+
+func sameHost(a, b string) bool { // compares canonical urls by host
+	aStruct, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	bStruct, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return aStruct.Host == bStruct.Host
+}
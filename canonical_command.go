@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/junwei890/rumbling/internal/database"
+)
+
+func runCanonicalizeCommand(ctx context.Context, args []string, db *database.Queries, canon canonicalizer, out io.Writer) error { // rumbling canonicalize [https|http], rewrites rows stored before urls were canonicalized
+	scheme := "https" // what keys without one are renamed to
+	if len(args) > 1 || (len(args) == 1 && args[0] != "https" && args[0] != "http") {
+		return errors.New("usage: canonicalize [https|http]")
+	} else if len(args) == 1 {
+		scheme = args[0]
+	}
+	other := "http"
+	if scheme == "http" {
+		other = "https"
+	}
+
+	urls, err := db.ListDataUrls(ctx)
+	if err != nil {
+		return err
+	}
+
+outer:
+	for _, oldUrl := range urls {
+		candidates := []string{oldUrl}
+		if !strings.Contains(oldUrl, "://") { // normalizeURL stored host and path only, the page may have been either
+			candidates = []string{scheme + "://" + oldUrl, other + "://" + oldUrl}
+		}
+		newUrls := []string{}
+		for _, candidate := range candidates {
+			newUrl, err := canon.canonicalize(candidate)
+			if err != nil {
+				fmt.Fprintf(out, "kept\t%s\t%v\n", oldUrl, err)
+				continue outer
+			}
+			newUrls = append(newUrls, newUrl)
+		}
+		newUrl := newUrls[0]
+		if newUrl == oldUrl {
+			continue
+		}
+
+		for _, storedUrl := range newUrls {
+			_, err = db.RetrieveData(ctx, storedUrl)
+			if err == nil { // a re-crawl already stored the page under its new key, that row is the newer one
+				if err := db.DeleteData(ctx, oldUrl); err != nil {
+					return err
+				}
+				fmt.Fprintf(out, "dropped\t%s\t%s\n", oldUrl, storedUrl)
+				continue outer
+			} else if !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+		}
+
+		if err := db.RenameData(ctx, database.RenameDataParams{
+			NewUrl: newUrl,
+			OldUrl: oldUrl,
+		}); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "renamed\t%s\t%s\n", oldUrl, newUrl)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/junwei890/rumbling/internal/database"
+)
+
+func TestCanonicalizeCommand(t *testing.T) {
+	db := &fakeDB{
+		mu: &sync.Mutex{},
+		answer: func(name string, args []driver.Value) [][]driver.Value {
+			switch {
+			case name == "ListDataUrls":
+				return [][]driver.Value{{"www.hello.com/world"}, {"www.hello.com/about"}, {"www.hello.com/blog"}, {"HTTP://Example.com:80/a/"}, {"http://example.com/b?utm_source=x"}, {"http://example.com/c"}}
+			case name == "RetrieveData" && args[0] == "http://example.com/b": // re-crawled since the upgrade
+				return [][]driver.Value{{"http://example.com/b", "b", nil, nil, nil, nil}}
+			case name == "RetrieveData" && args[0] == "http://www.hello.com/about": // re-crawled over http
+				return [][]driver.Value{{"http://www.hello.com/about", "about", nil, nil, nil, nil}}
+			case name == "RetrieveData" && args[0] == "https://www.hello.com/blog":
+				return [][]driver.Value{{"https://www.hello.com/blog", "blog", nil, nil, nil, nil}}
+			}
+			return nil
+		},
+	}
+
+	out := &strings.Builder{}
+	if err := runCanonicalizeCommand(context.Background(), nil, database.New(sql.OpenDB(fakeConnector{db})), defaultCanonicalizer, out); err != nil {
+		t.Fatalf("canonicalize failed, unexpected error: %v", err)
+	}
+
+	renamed := [][]driver.Value{}
+	for _, row := range db.written("UPDATE", "data") {
+		renamed = append(renamed, []driver.Value{row["where.url"], row["url"]})
+	}
+	deleted := []driver.Value{}
+	for _, row := range db.written("DELETE FROM", "data") {
+		deleted = append(deleted, row["url"])
+	}
+	if expected := [][]driver.Value{{"www.hello.com/world", "https://www.hello.com/world"}, {"HTTP://Example.com:80/a/", "http://example.com/a"}}; !reflect.DeepEqual(renamed, expected) {
+		t.Errorf("canonicalize failed, %v != %v", renamed, expected)
+	}
+	if expected := []driver.Value{"www.hello.com/about", "www.hello.com/blog", "http://example.com/b?utm_source=x"}; !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("canonicalize failed, %v != %v", deleted, expected)
+	}
+	if expected := "renamed\twww.hello.com/world\thttps://www.hello.com/world\ndropped\twww.hello.com/about\thttp://www.hello.com/about\ndropped\twww.hello.com/blog\thttps://www.hello.com/blog\nrenamed\tHTTP://Example.com:80/a/\thttp://example.com/a\ndropped\thttp://example.com/b?utm_source=x\thttp://example.com/b\n"; out.String() != expected {
+		t.Errorf("canonicalize failed, %q != %q", out.String(), expected)
+	}
+}
+
+func TestCanonicalizeCommandScheme(t *testing.T) {
+	testCases := []struct {
+		name         string
+		args         []string
+		expected     string
+		errorPresent bool
+	}{
+		{
+			name:     "test case 1",
+			args:     nil,
+			expected: "https://www.hello.com/world",
+		},
+		{
+			name:     "test case 2",
+			args:     []string{"http"},
+			expected: "http://www.hello.com/world",
+		},
+		{
+			name:         "test case 3",
+			args:         []string{"ftp"},
+			errorPresent: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			db := &fakeDB{
+				mu: &sync.Mutex{},
+				answer: func(name string, args []driver.Value) [][]driver.Value {
+					if name == "ListDataUrls" {
+						return [][]driver.Value{{"www.hello.com/world"}}
+					}
+					return nil
+				},
+			}
+			err := runCanonicalizeCommand(context.Background(), testCase.args, database.New(sql.OpenDB(fakeConnector{db})), defaultCanonicalizer, &strings.Builder{})
+			if (err != nil) != testCase.errorPresent {
+				t.Errorf("%s failed, expecting err = %v", testCase.name, err)
+			} else if rows := db.written("UPDATE", "data"); err == nil && (len(rows) != 1 || rows[0]["url"] != testCase.expected) {
+				t.Errorf("%s failed, %v != %v", testCase.name, rows, testCase.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	testCases := []struct {
+		name         string
+		canon        canonicalizer
+		url          string
+		expected     string
+		errorPresent bool
+	}{
+		{
+			name:     "test case 1",
+			canon:    defaultCanonicalizer,
+			url:      "HTTPS://Example.COM/Docs",
+			expected: "https://example.com/Docs",
+		},
+		{
+			name:     "test case 2",
+			canon:    defaultCanonicalizer,
+			url:      "http://example.com:80/a",
+			expected: "http://example.com/a",
+		},
+		{
+			name:     "test case 3",
+			canon:    defaultCanonicalizer,
+			url:      "https://example.com:443/",
+			expected: "https://example.com",
+		},
+		{
+			name:     "test case 4",
+			canon:    defaultCanonicalizer,
+			url:      "https://example.com:8443/a",
+			expected: "https://example.com:8443/a",
+		},
+		{
+			name:     "test case 5",
+			canon:    defaultCanonicalizer,
+			url:      "https://example.com/a/b/../c/./d",
+			expected: "https://example.com/a/c/d",
+		},
+		{
+			name:     "test case 6",
+			canon:    defaultCanonicalizer,
+			url:      "https://example.com/../../a/..",
+			expected: "https://example.com",
+		},
+		{
+			name:     "test case 7",
+			canon:    defaultCanonicalizer,
+			url:      "https://example.com/list?page=2&sort=asc",
+			expected: "https://example.com/list?page=2&sort=asc",
+		},
+		{
+			name:     "test case 8",
+			canon:    defaultCanonicalizer,
+			url:      "https://example.com/list?sort=asc&page=2",
+			expected: "https://example.com/list?page=2&sort=asc",
+		},
+		{
+			name:     "test case 9",
+			canon:    defaultCanonicalizer,
+			url:      "https://example.com/post?utm_source=x&id=7&UTM_Medium=y&PHPSESSID=abc&fbclid=z",
+			expected: "https://example.com/post?id=7",
+		},
+		{
+			name:     "test case 10",
+			canon:    defaultCanonicalizer,
+			url:      "https://example.com/post?utm_source=x",
+			expected: "https://example.com/post",
+		},
+		{
+			name: "test case 11",
+			canon: canonicalizer{
+				keepParams: []string{"page"},
+			},
+			url:      "https://example.com/list?ref=nav&page=3&lang=en",
+			expected: "https://example.com/list?page=3",
+		},
+		{
+			name: "test case 12",
+			canon: canonicalizer{
+				wwwEquivalent: true,
+			},
+			url:      "https://WWW.example.com/a",
+			expected: "https://example.com/a",
+		},
+		{
+			name:     "test case 13",
+			canon:    defaultCanonicalizer,
+			url:      "https://www.example.com/a",
+			expected: "https://www.example.com/a",
+		},
+		{
+			name:     "test case 14",
+			canon:    defaultCanonicalizer,
+			url:      "https://Bücher.example/katalog",
+			expected: "https://xn--bcher-kva.example/katalog",
+		},
+		{
+			name:     "test case 15",
+			canon:    defaultCanonicalizer,
+			url:      "https://example.com/a#section",
+			expected: "https://example.com/a",
+		},
+		{
+			name:     "test case 16",
+			canon:    defaultCanonicalizer,
+			url:      "http://[::1]:80/a",
+			expected: "http://[::1]/a",
+		},
+		{
+			name:     "test case 17",
+			canon:    defaultCanonicalizer,
+			url:      "https://My_Site.example.com/a",
+			expected: "https://my_site.example.com/a",
+		},
+		{
+			name:     "test case 18",
+			canon:    defaultCanonicalizer,
+			url:      "https://Bücher.example/",
+			expected: "https://xn--bcher-kva.example",
+		},
+		{
+			name:         "test case 19",
+			canon:        defaultCanonicalizer,
+			url:          "mailto:someone@example.com",
+			errorPresent: true,
+		},
+		{
+			name:         "test case 20",
+			canon:        defaultCanonicalizer,
+			url:          "/relative/path",
+			errorPresent: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := testCase.canon.canonicalize(testCase.url)
+			if (err != nil) != testCase.errorPresent {
+				t.Errorf("%s failed, expecting err = %v", testCase.name, err)
+			} else if result != testCase.expected {
+				t.Errorf("%s failed, %s != %s", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
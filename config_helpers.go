@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func envString(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return value
+}
+
+func envFloat(key string, fallback float64) float64 {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return value
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return value
+}
+
+func envBool(key string, fallback bool) bool {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return value
+}
+
+func envList(key string, fallback []string) []string { // comma separated
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	values := []string{}
+	for value := range strings.SplitSeq(raw, ",") {
+		if clean := strings.TrimSpace(value); clean != "" {
+			values = append(values, clean)
+		}
+	}
+	return values
+}
+
+func envHeaders(key string) http.Header { // "Name: value" pairs separated by |, since header values may contain commas
+	header := http.Header{}
+	for pair := range strings.SplitSeq(os.Getenv(key), "|") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			log.Fatalf("invalid %s: %q is not a header", key, pair)
+		}
+		header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return header
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var nonText = regexp.MustCompile(`[^a-zA-Z0-9 .,!?]+`) // leave letters, numbers, single space and punctuation
+
+var skippedElements = map[atom.Atom]struct{}{ // never rendered as text
+	atom.Script:   {},
+	atom.Style:    {},
+	atom.Noscript: {},
+	atom.Template: {},
+	atom.Svg:      {},
+	atom.Iframe:   {},
+	atom.Object:   {},
+	atom.Select:   {},
+	atom.Textarea: {},
+}
+
+var blockElements = map[atom.Atom]struct{}{ // text on either side of these belongs to a different block
+	atom.Address:    {},
+	atom.Article:    {},
+	atom.Aside:      {},
+	atom.Blockquote: {},
+	atom.Body:       {},
+	atom.Caption:    {},
+	atom.Dd:         {},
+	atom.Details:    {},
+	atom.Dialog:     {},
+	atom.Div:        {},
+	atom.Dl:         {},
+	atom.Dt:         {},
+	atom.Fieldset:   {},
+	atom.Figcaption: {},
+	atom.Figure:     {},
+	atom.Footer:     {},
+	atom.Form:       {},
+	atom.H1:         {},
+	atom.H2:         {},
+	atom.H3:         {},
+	atom.H4:         {},
+	atom.H5:         {},
+	atom.H6:         {},
+	atom.Header:     {},
+	atom.Hr:         {},
+	atom.Li:         {},
+	atom.Main:       {},
+	atom.Nav:        {},
+	atom.Ol:         {},
+	atom.P:          {},
+	atom.Pre:        {},
+	atom.Section:    {},
+	atom.Summary:    {},
+	atom.Table:      {},
+	atom.Td:         {},
+	atom.Th:         {},
+	atom.Tr:         {},
+	atom.Ul:         {},
+}
+
+var headingElements = map[atom.Atom]struct{}{
+	atom.H1: {},
+	atom.H2: {},
+	atom.H3: {},
+	atom.H4: {},
+	atom.H5: {},
+	atom.H6: {},
+}
+
+type pageContent struct {
+	title       string
+	description string
+	headings    []string // h1 to h6 in document order
+	blocks      []string // block level text in document order, whitespace collapsed
+	main        []string // the blocks the main content detector kept
+}
+
+func extractContent(htmlTree *html.Node) pageContent {
+	content := pageContent{}
+	ogDescription := ""
+	inline := []string{} // text of the block being built
+	linkChars := 0       // how much of it sits inside links
+	inLink := 0
+	owners := []*html.Node{htmlTree} // open block elements, the innermost owns the text being built
+	records := []textBlock{}
+
+	flush := func() string {
+		text := collapseSpace(strings.Join(inline, "")) // inline markup can sit inside a word, so only the page's own whitespace separates
+		if text != "" {
+			content.blocks = append(content.blocks, text)
+			records = append(records, textBlock{
+				text:  text,
+				links: min(linkChars, len(text)),
+				node:  owners[len(owners)-1],
+			})
+		}
+		inline = inline[:0]
+		linkChars = 0
+		return text
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			inline = append(inline, n.Data)
+			if inLink > 0 {
+				linkChars += len(collapseSpace(n.Data))
+			}
+			return
+		} else if n.Type != html.ElementNode && n.Type != html.DocumentNode {
+			return
+		}
+
+		if _, ok := skippedElements[n.DataAtom]; ok {
+			return
+		}
+		switch n.DataAtom {
+		case atom.Title:
+			if content.title == "" {
+				content.title = collapseSpace(nodeText(n))
+			}
+			return
+		case atom.Meta:
+			name, _ := attrValue(n, "name")
+			property, _ := attrValue(n, "property")
+			value, _ := attrValue(n, "content")
+			if strings.EqualFold(name, "description") && content.description == "" {
+				content.description = collapseSpace(value)
+			} else if strings.EqualFold(property, "og:description") && ogDescription == "" {
+				ogDescription = collapseSpace(value)
+			}
+			return
+		case atom.Br:
+			inline = append(inline, " ")
+			return
+		case atom.Img:
+			return
+		}
+
+		_, block := blockElements[n.DataAtom]
+		if block {
+			flush()
+			owners = append(owners, n)
+		} else if n.DataAtom == atom.A {
+			inLink++
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if block {
+			text := flush()
+			owners = owners[:len(owners)-1]
+			if _, ok := headingElements[n.DataAtom]; ok && text != "" {
+				content.headings = append(content.headings, text)
+			}
+		} else if n.DataAtom == atom.A {
+			inLink--
+		}
+	}
+	walk(htmlTree)
+	flush()
+	content.main = mainBlocks(records)
+
+	if content.description == "" { // pages that only describe themselves to social previews
+		content.description = ogDescription
+	}
+	return content
+}
+
+func nodeText(n *html.Node) string {
+	text := []string{}
+	for child := range n.Descendants() {
+		if child.Type == html.TextNode {
+			text = append(text, child.Data)
+		}
+	}
+	return strings.Join(text, "")
+}
+
+func collapseSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func cleanText(blocks []string) string { // lowercased and stripped for rake, each block ends a sentence so headings don't run into paragraphs
+	sentences := []string{}
+	for _, block := range blocks {
+		clean := collapseSpace(nonText.ReplaceAllString(strings.ToLower(block), ""))
+		if clean == "" {
+			continue
+		}
+		if !strings.ContainsAny(clean[len(clean)-1:], ".,!?") {
+			clean += "."
+		}
+		sentences = append(sentences, clean)
+	}
+	return strings.Join(sentences, " ")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestExtractContent(t *testing.T) {
+	testCases := []struct {
+		name     string
+		document string
+		expected pageContent
+	}{
+		{
+			name: "test case 1",
+			document: `<html><head><title> Field  Notes </title><meta name="Description" content="notes from the field"></head>
+<body><h1>Field <em>notes</em></h1><p>Read the <a href="/guide">guide</a> first.</p><ul><li>one</li><li>two<br>three</li></ul></body></html>`,
+			expected: pageContent{
+				title:       "Field Notes",
+				description: "notes from the field",
+				headings:    []string{"Field notes"},
+				blocks:      []string{"Field notes", "Read the guide first.", "one", "two three"},
+				main:        []string{"Field notes", "Read the guide first.", "one", "two three"},
+			},
+		},
+		{
+			name: "test case 2",
+			document: `<html><head><meta property="og:description" content="shared preview"><style>p { color: red }</style></head>
+<body><script>var x = 1;</script><table><tr><th>name</th><td>value</td></tr></table><blockquote>quoted <b>text</b></blockquote><h2>Later</h2>tail</body></html>`,
+			expected: pageContent{
+				description: "shared preview",
+				headings:    []string{"Later"},
+				blocks:      []string{"name", "value", "quoted text", "Later", "tail"},
+				main:        []string{"name", "value", "quoted text", "Later", "tail"},
+			},
+		},
+		{
+			name:     "test case 3",
+			document: `<div>outer <p>inner</p> after</div>`,
+			expected: pageContent{
+				blocks: []string{"outer", "inner", "after"},
+				main:   []string{"outer", "inner", "after"},
+			},
+		},
+		{
+			name:     "test case 4",
+			document: `<p>Go<b>lang</b> is covered in the <a href="/guide">guide</a>. Read it<br>twice.</p>`,
+			expected: pageContent{
+				blocks: []string{"Golang is covered in the guide. Read it twice."},
+				main:   []string{"Golang is covered in the guide. Read it twice."},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			htmlTree, err := html.Parse(strings.NewReader(testCase.document))
+			if err != nil {
+				t.Fatalf("%s failed, unexpected error: %v", testCase.name, err)
+			}
+			if result := extractContent(htmlTree); !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("%s failed, %#v != %#v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestCleanText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		blocks   []string
+		expected string
+	}{
+		{
+			name:     "test case 1",
+			blocks:   []string{"Getting Started", "Install the CLI, then run it!"},
+			expected: "getting started. install the cli, then run it!",
+		},
+		{
+			name:     "test case 2",
+			blocks:   []string{"© 2025", "Price: $5 — today"},
+			expected: "2025. price 5 today.",
+		},
+		{
+			name:     "test case 3",
+			blocks:   nil,
+			expected: "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if result := cleanText(testCase.blocks); result != testCase.expected {
+				t.Errorf("%s failed, %s != %s", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (c *apiConfig) getCrawl(w http.ResponseWriter, req *http.Request) {
+	type resData struct {
+		ID           string          `json:"id"`
+		Url          string          `json:"url"`
+		Options      json.RawMessage `json:"options"`
+		State        string          `json:"state"`
+		PagesVisited int64           `json:"pages_visited"`
+		PagesStored  int64           `json:"pages_stored"`
+		PagesSkipped int64           `json:"pages_skipped"`
+		Errors       int64           `json:"errors"`
+		LastError    string          `json:"last_error,omitempty"`
+		CreatedAt    time.Time       `json:"created_at"`
+		StartedAt    *time.Time      `json:"started_at,omitempty"`
+		FinishedAt   *time.Time      `json:"finished_at,omitempty"`
+		Duration     float64         `json:"duration_seconds"`
+	}
+
+	crawl, err := c.db.GetCrawl(req.Context(), req.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponseWriter(w, http.StatusNotFound, errors.New("crawl not found"))
+		return
+	} else if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := resData{
+		ID:           crawl.ID,
+		Url:          crawl.Url,
+		Options:      json.RawMessage(crawl.Options),
+		State:        crawl.State,
+		PagesVisited: crawl.PagesVisited,
+		PagesStored:  crawl.PagesStored,
+		PagesSkipped: crawl.PagesSkipped,
+		Errors:       crawl.Errors,
+		LastError:    crawl.LastError.String,
+		CreatedAt:    crawl.CreatedAt,
+	}
+	if crawl.StartedAt.Valid {
+		res.StartedAt = &crawl.StartedAt.Time
+		end := time.Now()
+		if crawl.FinishedAt.Valid {
+			res.FinishedAt = &crawl.FinishedAt.Time
+			end = crawl.FinishedAt.Time
+		}
+		res.Duration = end.Sub(crawl.StartedAt.Time).Seconds()
+	}
+
+	jsonResponseWriter(w, http.StatusOK, res)
+}
+
+func (c *apiConfig) deleteCrawl(w http.ResponseWriter, req *http.Request) {
+	type resData struct {
+		ID    string `json:"id"`
+		State string `json:"state"`
+	}
+
+	crawl, err := c.db.GetCrawl(req.Context(), req.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponseWriter(w, http.StatusNotFound, errors.New("crawl not found"))
+		return
+	} else if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !c.cancelCrawl(crawl.ID) {
+		errorResponseWriter(w, http.StatusConflict, errors.New("crawl already "+crawl.State))
+		return
+	}
+
+	state := crawl.State
+	if state == crawlQueued { // no worker has picked it up yet, so nothing else will record the cancellation soon
+		if err := c.db.CancelQueuedCrawl(req.Context(), crawl.ID); err != nil {
+			errorResponseWriter(w, http.StatusInternalServerError, err)
+			return
+		}
+		state = crawlCancelled
+	}
+
+	jsonResponseWriter(w, http.StatusAccepted, resData{
+		ID:    crawl.ID,
+		State: state,
+	})
+}
+
+func (c *apiConfig) getCrawlDuplicates(w http.ResponseWriter, req *http.Request) {
+	type duplicate struct {
+		Url          string `json:"url"`
+		CanonicalUrl string `json:"canonical_url"`
+		Distance     int64  `json:"distance"`
+	}
+	type resData struct {
+		ID         string      `json:"id"`
+		Duplicates []duplicate `json:"duplicates"`
+	}
+
+	crawl, err := c.db.GetCrawl(req.Context(), req.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponseWriter(w, http.StatusNotFound, errors.New("crawl not found"))
+		return
+	} else if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	rows, err := c.db.ListDuplicates(req.Context(), crawl.ID)
+	if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := resData{
+		ID:         crawl.ID,
+		Duplicates: []duplicate{},
+	}
+	for _, row := range rows {
+		res.Duplicates = append(res.Duplicates, duplicate{
+			Url:          row.Url,
+			CanonicalUrl: row.CanonicalUrl,
+			Distance:     row.Distance,
+		})
+	}
+	jsonResponseWriter(w, http.StatusOK, res)
+}
+
+func (c *apiConfig) getCrawlRedirects(w http.ResponseWriter, req *http.Request) {
+	type hop struct {
+		From   string `json:"from"`
+		To     string `json:"to"`
+		Status int64  `json:"status"`
+	}
+	type chain struct {
+		Url      string `json:"url"`
+		FinalUrl string `json:"final_url"`
+		Hops     []hop  `json:"hops"`
+	}
+	type resData struct {
+		ID        string  `json:"id"`
+		Redirects []chain `json:"redirects"`
+	}
+
+	crawl, err := c.db.GetCrawl(req.Context(), req.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponseWriter(w, http.StatusNotFound, errors.New("crawl not found"))
+		return
+	} else if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	rows, err := c.db.ListRedirects(req.Context(), crawl.ID)
+	if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := resData{
+		ID:        crawl.ID,
+		Redirects: []chain{},
+	}
+	for _, row := range rows { // rows come ordered by url then hop, so each chain is contiguous
+		if len(res.Redirects) == 0 || res.Redirects[len(res.Redirects)-1].Url != row.Url {
+			res.Redirects = append(res.Redirects, chain{
+				Url:  row.Url,
+				Hops: []hop{},
+			})
+		}
+		curr := &res.Redirects[len(res.Redirects)-1]
+		curr.Hops = append(curr.Hops, hop{
+			From:   row.FromUrl,
+			To:     row.ToUrl,
+			Status: row.StatusCode,
+		})
+		curr.FinalUrl = row.ToUrl
+	}
+	jsonResponseWriter(w, http.StatusOK, res)
+}
+
+func (c *apiConfig) getCrawlBrokenLinks(w http.ResponseWriter, req *http.Request) {
+	type source struct {
+		Url        string `json:"url"`
+		AnchorText string `json:"anchor_text"`
+	}
+	type brokenLink struct {
+		Url        string   `json:"url"`
+		Status     *int64   `json:"status"`
+		ErrorClass string   `json:"error_class"`
+		Error      string   `json:"error"`
+		LinkedFrom []source `json:"linked_from"`
+	}
+	type resData struct {
+		ID          string       `json:"id"`
+		BrokenLinks []brokenLink `json:"broken_links"`
+	}
+
+	format := req.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		errorResponseWriter(w, http.StatusBadRequest, errors.New("format must be json or csv"))
+		return
+	}
+
+	crawl, err := c.db.GetCrawl(req.Context(), req.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponseWriter(w, http.StatusNotFound, errors.New("crawl not found"))
+		return
+	} else if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	rows, err := c.db.ListBrokenLinks(req.Context(), crawl.ID)
+	if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if format == "csv" { // one row per linking page, so spreadsheets can filter by either side
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="broken-links-`+crawl.ID+`.csv"`)
+		w.WriteHeader(http.StatusOK)
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"url", "status", "error_class", "error", "source_url", "anchor_text"})
+		for _, row := range rows {
+			status := ""
+			if row.StatusCode.Valid {
+				status = strconv.FormatInt(row.StatusCode.Int64, 10)
+			}
+			writer.Write([]string{row.Url, status, row.ErrorClass, row.Error.String, row.SourceUrl.String, row.AnchorText.String})
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	res := resData{
+		ID:          crawl.ID,
+		BrokenLinks: []brokenLink{},
+	}
+	for _, row := range rows { // rows come ordered by url, so each target's sources are contiguous
+		if len(res.BrokenLinks) == 0 || res.BrokenLinks[len(res.BrokenLinks)-1].Url != row.Url {
+			link := brokenLink{
+				Url:        row.Url,
+				ErrorClass: row.ErrorClass,
+				Error:      row.Error.String,
+				LinkedFrom: []source{},
+			}
+			if row.StatusCode.Valid {
+				link.Status = &row.StatusCode.Int64
+			}
+			res.BrokenLinks = append(res.BrokenLinks, link)
+		}
+		if row.SourceUrl.Valid {
+			curr := &res.BrokenLinks[len(res.BrokenLinks)-1]
+			curr.LinkedFrom = append(curr.LinkedFrom, source{
+				Url:        row.SourceUrl.String,
+				AnchorText: row.AnchorText.String,
+			})
+		}
+	}
+	jsonResponseWriter(w, http.StatusOK, res)
+}
+
+func (c *apiConfig) getCrawlScope(w http.ResponseWriter, req *http.Request) {
+	type decision struct {
+		Url     string `json:"url"`
+		InScope bool   `json:"in_scope"`
+		Reason  string `json:"reason"`
+	}
+	type resData struct {
+		ID        string     `json:"id"`
+		Decisions []decision `json:"decisions"`
+	}
+
+	filter := req.URL.Query().Get("in_scope") // optional, narrows the report to one side
+	var want bool
+	if filter != "" {
+		parsed, err := strconv.ParseBool(filter)
+		if err != nil {
+			errorResponseWriter(w, http.StatusBadRequest, errors.New("in_scope must be true or false"))
+			return
+		}
+		want = parsed
+	}
+
+	crawl, err := c.db.GetCrawl(req.Context(), req.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponseWriter(w, http.StatusNotFound, errors.New("crawl not found"))
+		return
+	} else if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	rows, err := c.db.ListScopeDecisions(req.Context(), crawl.ID)
+	if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := resData{
+		ID:        crawl.ID,
+		Decisions: []decision{},
+	}
+	for _, row := range rows {
+		if filter != "" && row.InScope != want {
+			continue
+		}
+		res.Decisions = append(res.Decisions, decision{
+			Url:     row.Url,
+			InScope: row.InScope,
+			Reason:  row.Reason,
+		})
+	}
+	jsonResponseWriter(w, http.StatusOK, res)
+}
+
+func (c *apiConfig) getCrawlSkipped(w http.ResponseWriter, req *http.Request) {
+	type skipped struct {
+		Url    string `json:"url"`
+		Reason string `json:"reason"`
+	}
+	type resData struct {
+		ID      string    `json:"id"`
+		Skipped []skipped `json:"skipped"`
+	}
+
+	crawl, err := c.db.GetCrawl(req.Context(), req.PathValue("id"))
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponseWriter(w, http.StatusNotFound, errors.New("crawl not found"))
+		return
+	} else if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	rows, err := c.db.ListSkippedUrls(req.Context(), crawl.ID)
+	if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := resData{
+		ID:      crawl.ID,
+		Skipped: []skipped{},
+	}
+	for _, row := range rows {
+		res.Skipped = append(res.Skipped, skipped{
+			Url:    row.Url,
+			Reason: row.Reason,
+		})
+	}
+	jsonResponseWriter(w, http.StatusOK, res)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetCrawl(t *testing.T) {
+	started := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	db := &fakeDB{
+		mu: &sync.Mutex{},
+		answer: func(name string, args []driver.Value) [][]driver.Value {
+			if name != "GetCrawl" || args[0] != "job" {
+				return nil
+			}
+			return [][]driver.Value{{
+				"job", "https://example.com", crawlDone, int64(3), int64(2), int64(1), int64(1), "fetch failed",
+				started, started, started.Add(90 * time.Second), started.Add(90 * time.Second), `{"max_pages":5}`,
+			}}
+		},
+	}
+	api := testAPI(db, 1)
+
+	testCases := []struct {
+		name           string
+		id             string
+		expectedStatus int
+		expectedState  string
+	}{
+		{
+			name:           "test case 1",
+			id:             "job",
+			expectedStatus: http.StatusOK,
+			expectedState:  crawlDone,
+		},
+		{
+			name:           "test case 2",
+			id:             "missing",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/crawls/"+testCase.id, nil)
+			req.SetPathValue("id", testCase.id)
+			rec := httptest.NewRecorder()
+			api.getCrawl(rec, req)
+			if rec.Code != testCase.expectedStatus {
+				t.Fatalf("%s failed, %v != %v", testCase.name, rec.Code, testCase.expectedStatus)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			res := struct {
+				ID          string          `json:"id"`
+				State       string          `json:"state"`
+				Options     json.RawMessage `json:"options"`
+				PagesStored int64           `json:"pages_stored"`
+				LastError   string          `json:"last_error"`
+				Duration    float64         `json:"duration_seconds"`
+			}{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+				t.Fatalf("%s failed, unexpected error: %v", testCase.name, err)
+			}
+			if res.ID != testCase.id || res.State != testCase.expectedState || res.PagesStored != 2 || res.LastError != "fetch failed" {
+				t.Errorf("%s failed, %+v", testCase.name, res)
+			}
+			if string(res.Options) != `{"max_pages":5}` {
+				t.Errorf("%s failed, %s != %s", testCase.name, res.Options, `{"max_pages":5}`)
+			}
+			if res.Duration != 90 {
+				t.Errorf("%s failed, %v != %v", testCase.name, res.Duration, 90)
+			}
+		})
+	}
+}
@@ -2,132 +2,644 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"log"
 	"net/url"
-	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/junwei890/rumbling/internal/database"
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 )
 
-func (c *crawlerConfig) initCrawl(baseUrl string) {
-	c.wg.Add(1)
-	go c.crawlPage(baseUrl) // recursive
+func (c *crawlerConfig) initCrawl(ctx context.Context, baseUrl string) { // breadth first, so the budget covers the top levels of a site first
+	var err error
+	c.scope, err = newScope(baseUrl, c.opts, c.canon)
+	if err != nil {
+		c.fail(baseUrl, err)
+		return
+	}
+
+	front := newFrontier(c.frontierSize)
+	stop := context.AfterFunc(ctx, front.close) // cancelling wakes up idle workers
+	defer stop()
+
+	decisions := c.enqueue(front, baseUrl, 0, nil)
+	if c.opts.depth() >= 1 {
+		for _, seed := range c.sitemapSeeds(ctx) { // pages only reachable through sitemaps, treated as one hop from the seed
+			decisions = c.enqueue(front, seed, 1, decisions)
+		}
+	}
+	c.recordScopes(ctx, decisions)
+
+	for range c.opts.Concurrency {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.worker(ctx, front)
+		}()
+	}
 	c.wg.Wait()
 }
 
-func (c *crawlerConfig) dataFromHTML(normCurrUrl, htmlBody string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+func (c *crawlerConfig) enqueue(front *frontier, rawUrl string, depth int, decisions []database.InsertScopeDecisionParams) []database.InsertScopeDecisionParams { // appends the scope decisions still to be recorded, like append
+	normUrl, err := c.canon.canonicalize(rawUrl)
+	if err != nil {
+		return decisions
+	}
+	decision := c.scope.check(normUrl)
+	if c.decide(normUrl, decision) {
+		decisions = append(decisions, database.InsertScopeDecisionParams{
+			CrawlID: c.crawlID,
+			Url:     normUrl,
+			InScope: decision.inScope,
+			Reason:  decision.reason,
+		})
+	}
+	if !decision.inScope { // don't spend frontier space on urls we would never fetch
+		if !decision.offHost {
+			c.skip(normUrl, decision.reason)
+		}
+		return decisions
+	}
+	front.push(frontierItem{
+		rawUrl:  rawUrl,
+		normUrl: normUrl,
+		depth:   depth,
+	})
+	return decisions
+}
+
+func (c *crawlerConfig) worker(ctx context.Context, front *frontier) {
+	for {
+		item, ok := front.pop()
+		if !ok {
+			return
+		}
+		c.visit(ctx, front, item)
+		front.done()
+	}
+}
 
-	htmlTree, err := html.Parse(strings.NewReader(htmlBody))
+func (c *crawlerConfig) visit(ctx context.Context, front *frontier, item frontierItem) {
+	if ctx.Err() != nil || c.maxReached() {
+		front.close() // nothing left to do, let the other workers drain
+		return
+	}
+
+	delay, ok := c.admit(ctx, item)
+	if !ok {
+		return
+	}
+	links := c.crawlPage(ctx, item.rawUrl, item.normUrl, item.depth, delay)
+	if item.depth >= c.opts.depth() {
+		return
+	}
+	decisions := []database.InsertScopeDecisionParams{}
+	for _, link := range links {
+		decisions = c.enqueue(front, link, item.depth+1, decisions)
+	}
+	c.recordScopes(ctx, decisions)
+}
+
+func (c *crawlerConfig) dataFromHTML(ctx context.Context, rawCurrUrl, normCurrUrl string, page fetchedPage, depth int) error { // parses without holding c.mu so workers don't serialize
+	htmlTree, err := html.Parse(strings.NewReader(page.body))
 	if err != nil {
 		return err
 	}
+	pageUrl, err := url.Parse(rawCurrUrl)
+	if err != nil {
+		return err
+	}
+	base := documentBase(pageUrl, htmlTree)
+	links := extractLinks(base, htmlTree)
 
-	content := []string{}
+	storeUrl := normCurrUrl // differs when the page names a canonical url
 	for n := range htmlTree.Descendants() {
-		if n.Type == html.ElementNode && n.DataAtom == atom.A {
-			for _, attr := range n.Attr {
-				if attr.Key == "href" {
-					if urlStruct, err := url.Parse(attr.Val); err != nil {
-						return err
-					} else if urlStruct.Hostname() == "" {
-						c.links[normCurrUrl] = append(c.links[normCurrUrl], c.domain.ResolveReference(urlStruct).String())
-					} else {
-						c.links[normCurrUrl] = append(c.links[normCurrUrl], attr.Val)
-					}
-				}
-			}
-		} else if n.Type == html.ElementNode && n.DataAtom == atom.P {
-			for child := n.FirstChild; child != nil; child = child.NextSibling {
-				if child.Type == html.TextNode {
-					re, err := regexp.Compile(`[^a-zA-Z0-9 .,!?]+`) // leave letters, numbers, single space and punctuation
-					if err != nil {
-						return err
-					}
-					clean := strings.TrimSpace(re.ReplaceAllString(strings.ToLower(child.Data), ""))
-					if clean != "" {
-						content = append(content, clean)
-					}
-				}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Link && c.canon.honorCanonical {
+			if canonical, ok := c.relCanonical(base, normCurrUrl, n); ok {
+				storeUrl = canonical
 			}
 		}
 	}
+	content := extractContent(htmlTree)
+
+	directives := robotsDirectives{}
+	followed := links
+	if !c.directives.ignores(normCurrUrl) {
+		directives = headerDirectives(page.robotsTag, c.directives.agent).merge(metaDirectives(htmlTree, c.directives.agent))
+		followed = followable(links)
+	}
+	if directives.nofollow { // the page vouches for none of its links, so they stay out of the graph too
+		links, followed = nil, nil
+		log.Printf("not following links on %s: nofollow", normCurrUrl)
+	}
+
+	c.mu.Lock()
+	c.links[normCurrUrl] = linkUrls(followed)
+	c.mu.Unlock()
+	key, err := c.storeKey(ctx, normCurrUrl) // where earlier crawls stored the page
+	if err != nil {
+		return err
+	}
+	if err := c.storeLinks(ctx, storeUrl, links); err != nil {
+		return err
+	}
+	if storeUrl != key { // validators, links and the row are looked up through this on the next crawl
+		if err := c.db.UpsertPageAlias(ctx, database.UpsertPageAliasParams{
+			Url:      normCurrUrl,
+			StoreUrl: storeUrl,
+		}); err != nil {
+			return err
+		}
+	}
 
-	clean := strings.TrimSpace(strings.Join(content, " "))
+	if directives.noindex { // drops what an earlier crawl stored, before the page asked not to be
+		log.Printf("not storing %s: noindex", normCurrUrl)
+		return c.db.DeleteData(ctx, key)
+	}
+
+	full := cleanText(content.blocks)
+	clean := full
+	if c.content.mode == contentMain && len(content.main) != 0 { // pages that are all chrome still get their text stored
+		clean = cleanText(content.main)
+	}
+	fullContent := sql.NullString{}
+	if c.content.keepFull {
+		fullContent = nullString(full)
+	}
 	if clean != "" {
-		if err := c.db.InsertData(context.Background(), database.InsertDataParams{
-			Url:     normCurrUrl,
-			Content: clean,
+		c.loadFingerprints(ctx, storeUrl)
+		hash := simhash(clean)
+		canonical, distance, duplicate := c.matchFingerprint(storeUrl, hash, len(strings.Fields(clean)))
+		if duplicate {
+			log.Printf("%s is a near-duplicate of %s", storeUrl, canonical)
+			if err := c.db.InsertDuplicate(ctx, database.InsertDuplicateParams{
+				CrawlID:      c.crawlID,
+				Url:          storeUrl,
+				CanonicalUrl: canonical,
+				Distance:     int64(distance),
+			}); err != nil {
+				return err
+			}
+			if c.dedup.mode == dedupSkip {
+				return nil
+			}
+		}
+
+		if err := c.db.UpsertData(ctx, database.UpsertDataParams{ // re-crawls refresh the existing row
+			Url:          storeUrl,
+			Content:      clean,
+			Depth:        int64(depth),
+			Etag:         nullString(page.validators.etag),
+			LastModified: nullString(page.validators.lastModified),
+			Simhash: sql.NullInt64{
+				Int64: int64(hash),
+				Valid: true,
+			},
+			CanonicalUrl: nullString(canonical),
+			Charset:      nullString(page.charset),
+			Title:        nullString(content.title),
+			Description:  nullString(content.description),
+			Headings:     nullString(strings.Join(content.headings, "\n")),
+			FullContent:  fullContent,
 		}); err != nil {
 			return err
 		}
+		c.mu.Lock()
+		c.stats.stored++
+		c.mu.Unlock()
 	}
 	return nil
 }
 
-func (c *crawlerConfig) urlVisited(normCurrUrl string) bool {
+func (c *crawlerConfig) loadFingerprints(ctx context.Context, normUrl string) { // pages from earlier crawls of a host count as canonicals too, loaded the first time the crawl stores there
+	urlStruct, err := url.Parse(normUrl)
+	if err != nil {
+		return
+	}
+	root := (&url.URL{Scheme: urlStruct.Scheme, Host: urlStruct.Host}).String()
+
+	c.mu.Lock()
+	ready, ok := c.roots[root]
+	if !ok {
+		ready = make(chan struct{})
+		c.roots[root] = ready
+	}
+	c.mu.Unlock()
+	if ok { // another worker loads them, comparing before it is done could miss a duplicate
+		select {
+		case <-ctx.Done():
+		case <-ready:
+		}
+		return
+	}
+	defer close(ready)
+
+	rows, err := c.db.ListFingerprints(ctx, likeEscaper.Replace(root)+"%")
+	if err != nil {
+		log.Printf("no stored fingerprints for %s: %v", root, err)
+		return
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	for _, row := range rows {
+		if row.Url != root && !strings.HasPrefix(row.Url, root+"/") { // LIKE also matches longer host names
+			continue
+		}
+		c.fingerprints = append(c.fingerprints, fingerprint{
+			url:  row.Url,
+			hash: uint64(row.Simhash.Int64),
+		})
+	}
+}
 
-	if _, ok := c.links[normCurrUrl]; ok {
-		return true
+func (c *crawlerConfig) relCanonical(base *url.URL, normCurrUrl string, n *html.Node) (string, bool) { // reads <link rel="canonical">, only trusted on the page's own host
+	rel, _ := attrValue(n, "rel")
+	href, ok := attrValue(n, "href")
+	if !slices.Contains(strings.Fields(strings.ToLower(rel)), "canonical") || !ok {
+		return "", false
 	}
-	c.links[normCurrUrl] = []string{}
-	return false
+
+	link, ok := resolveLink(base, href)
+	if !ok {
+		return "", false
+	}
+	canonical, err := c.canon.canonicalize(link)
+	if err != nil || !sameHost(canonical, normCurrUrl) {
+		return "", false
+	}
+	return canonical, true
 }
 
-func (c *crawlerConfig) maxReached() bool {
+func (c *crawlerConfig) storeKey(ctx context.Context, normCurrUrl string) (string, error) { // the url a page's row and links were stored under, its rel=canonical when it named one
+	storeUrl, err := c.db.GetPageAlias(ctx, normCurrUrl)
+	if errors.Is(err, sql.ErrNoRows) {
+		return normCurrUrl, nil
+	}
+	return storeUrl, err
+}
+
+func (c *crawlerConfig) previousValidators(ctx context.Context, normCurrUrl string) (validators, error) {
+	key, err := c.storeKey(ctx, normCurrUrl)
+	if err != nil {
+		return validators{}, err
+	}
+	row, err := c.db.GetValidators(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) { // first time we see this page
+		return validators{}, nil
+	} else if err != nil {
+		return validators{}, err
+	}
+	return validators{
+		etag:         row.Etag.String,
+		lastModified: row.LastModified.String,
+	}, nil
+}
+
+func (c *crawlerConfig) claimVisit(normCurrUrl string) bool { // checks and records a visit in one step so workers can't overshoot maxVisits
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if _, ok := c.links[normCurrUrl]; ok {
+		return false
+	}
 	if len(c.links) >= c.maxVisits {
-		return true
+		return false
 	}
-	return false
+	c.links[normCurrUrl] = []string{}
+	c.stats.visited++
+	return true
 }
 
-func (c *crawlerConfig) crawlPage(rawCurrUrl string) {
-	c.control <- struct{}{}
-	defer func() {
-		<-c.control
-		c.wg.Done()
-	}()
+func (c *crawlerConfig) storeLinks(ctx context.Context, sourceUrl string, links []pageLink) error { // replaces the page's outbound links, so the table holds the current graph
+	tx, err := c.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // a no-op once committed, readers never see the page without its links
+	db := c.db.WithTx(tx)
 
-	if c.maxReached() { // a series of early returns
-		return
+	if err := db.DeleteLinksFrom(ctx, sourceUrl); err != nil {
+		return err
+	}
+	for _, link := range links {
+		targetUrl, err := c.canon.canonicalize(link.url)
+		if err != nil {
+			continue
+		}
+		if err := db.InsertLink(ctx, database.InsertLinkParams{
+			CrawlID:    c.crawlID,
+			SourceUrl:  sourceUrl,
+			TargetUrl:  targetUrl,
+			AnchorText: link.anchor,
+			Rel:        link.rel,
+		}); err != nil {
+			return err
+		}
 	}
+	return tx.Commit()
+}
 
-	currStruct, err := url.Parse(rawCurrUrl)
+func (c *crawlerConfig) storedLinks(ctx context.Context, normCurrUrl, key string) error { // for pages that answered 304
+	if err := c.db.UpdateLinksCrawl(ctx, database.UpdateLinksCrawlParams{ // the page still vouches for them, so they count as found by this crawl
+		CrawlID:   c.crawlID,
+		SourceUrl: key,
+	}); err != nil {
+		return err
+	}
+	rows, err := c.db.ListOutboundLinks(ctx, key)
 	if err != nil {
-		return
+		return err
 	}
-	if c.domain.Hostname() != currStruct.Hostname() {
-		return
+	links := []string{}
+	for _, row := range rows {
+		if isNofollow(row.Rel) && !c.directives.ignores(normCurrUrl) {
+			continue
+		}
+		links = append(links, row.TargetUrl)
 	}
 
-	normCurrUrl, err := normalizeURL(rawCurrUrl)
-	if err != nil {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.links[normCurrUrl] = links
+	return nil
+}
+
+func (c *crawlerConfig) followRedirect(ctx context.Context) func(string) error { // a redirect may only lead where a link could
+	return func(rawUrl string) error {
+		normUrl, err := c.canon.canonicalize(rawUrl)
+		if err != nil || !c.scope.check(normUrl).inScope {
+			return errRedirectOutOfScope
+		}
+		urlStruct, err := url.Parse(rawUrl)
+		if err != nil {
+			return err
+		}
+		rules, err := c.robots.rulesFor(ctx, urlStruct)
+		if err != nil {
+			return err
+		}
+		if !rules.allowed(urlStruct) {
+			return errRedirectDisallowed
+		}
+		return nil
+	}
+}
+
+func (c *crawlerConfig) decide(normUrl string, decision scopeDecision) bool { // first decision per url wins, off-host links are recorded once per host since most pages link out a lot
+	key := normUrl
+	if urlStruct, err := url.Parse(normUrl); err == nil && decision.offHost {
+		key = urlStruct.Host
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.decided[key]; ok {
+		return false
+	}
+	c.decided[key] = struct{}{}
+	return true
+}
+
+func (c *crawlerConfig) recordScopes(ctx context.Context, decisions []database.InsertScopeDecisionParams) { // one transaction per page, not a round trip per link
+	if len(decisions) == 0 || ctx.Err() != nil {
 		return
 	}
-	if c.urlVisited(normCurrUrl) {
+	tx, err := c.conn.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("could not record scope decisions: %v", err)
 		return
 	}
+	defer tx.Rollback()
+	db := c.db.WithTx(tx)
+
+	for _, decision := range decisions {
+		if err := db.InsertScopeDecision(ctx, decision); err != nil {
+			log.Printf("could not record scope of %s: %v", decision.Url, err)
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("could not record scope decisions: %v", err)
+	}
+}
+
+func (c *crawlerConfig) claimRedirect(normUrl string) bool { // marks a redirect target visited so the frontier doesn't fetch it again
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.links[normUrl]; ok {
+		return false
+	}
+	c.links[normUrl] = []string{}
+	return true
+}
+
+func (c *crawlerConfig) recordFetch(ctx context.Context, normCurrUrl string, page fetchedPage, fetchErr error) error {
+	class, broken := classifyFetch(page, fetchErr)
+	status := page.status
+	if status == 0 && len(page.redirects) != 0 { // stopped mid chain, the last hop is the closest thing to an answer
+		status = page.redirects[len(page.redirects)-1].status
+	}
+	errMsg := ""
+	if fetchErr != nil {
+		errMsg = fetchErr.Error()
+	}
+	return c.db.InsertFetch(ctx, database.InsertFetchParams{
+		CrawlID: c.crawlID,
+		Url:     normCurrUrl,
+		StatusCode: sql.NullInt64{
+			Int64: int64(status),
+			Valid: status != 0,
+		},
+		ErrorClass: class,
+		Error:      nullString(errMsg),
+		Broken:     broken,
+	})
+}
+
+func (c *crawlerConfig) recordRedirects(ctx context.Context, normCurrUrl string, hops []redirectHop) error {
+	for i, hop := range hops {
+		if err := c.db.InsertRedirect(ctx, database.InsertRedirectParams{
+			CrawlID:    c.crawlID,
+			Url:        normCurrUrl,
+			Hop:        int64(i),
+			FromUrl:    hop.from,
+			ToUrl:      hop.to,
+			StatusCode: int64(hop.status),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *crawlerConfig) skip(normCurrUrl, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.skipped[normCurrUrl]; !ok {
+		c.skipped[normCurrUrl] = reason
+		c.unsaved = append(c.unsaved, database.InsertSkippedUrlParams{
+			CrawlID: c.crawlID,
+			Url:     normCurrUrl,
+			Reason:  reason,
+		})
+		c.stats.skipped++
+		log.Printf("skipping %s: %s", normCurrUrl, reason)
+	}
+}
+
+func (c *crawlerConfig) saveSkipped(ctx context.Context) error { // writes the skips since the last call in one transaction, runCrawl calls it with progress
+	c.mu.Lock()
+	pending := c.unsaved
+	c.unsaved = nil
+	c.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	err := func() error {
+		tx, err := c.conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		db := c.db.WithTx(tx)
+		for _, skipped := range pending {
+			if err := db.InsertSkippedUrl(ctx, skipped); err != nil {
+				return err
+			}
+		}
+		return tx.Commit()
+	}()
+	if err != nil { // kept for the next call
+		c.mu.Lock()
+		c.unsaved = append(pending, c.unsaved...)
+		c.mu.Unlock()
+	}
+	return err
+}
+
+func (c *crawlerConfig) fail(rawCurrUrl string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.errors++
+	c.stats.lastErr = err
+	log.Printf("error crawling %s: %v", rawCurrUrl, err)
+}
+
+func (c *crawlerConfig) snapshot() crawlStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	html, err := getHTML(rawCurrUrl)
+	return c.stats
+}
+
+func (c *crawlerConfig) maxReached() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.links) >= c.maxVisits {
+		return true
+	}
+	return false
+}
+
+func (c *crawlerConfig) admit(ctx context.Context, item frontierItem) (time.Duration, bool) { // reserves a visit, returns the delay to honor
+	currStruct, err := url.Parse(item.rawUrl) // a series of early returns, scope was already checked by enqueue
 	if err != nil {
-		return
+		return 0, false
 	}
-	if err := c.dataFromHTML(normCurrUrl, html); err != nil {
-		return
+
+	rules, err := c.robots.rulesFor(ctx, currStruct)
+	if err != nil {
+		return 0, false
+	}
+	if !rules.allowed(currStruct) {
+		c.skip(item.normUrl, "disallowed by robots.txt")
+		return 0, false
 	}
 
-	for _, link := range c.links[normCurrUrl] {
-		c.wg.Add(1)
-		log.Printf("crawling %s", link)
-		go c.crawlPage(link)
+	if !c.claimVisit(item.normUrl) {
+		return 0, false
+	}
+	return max(rules.crawlDelay, c.opts.delay()), true
+}
+
+func (c *crawlerConfig) crawlPage(ctx context.Context, rawCurrUrl, normCurrUrl string, depth int, delay time.Duration) []string {
+	prev, err := c.previousValidators(ctx, normCurrUrl)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.fail(rawCurrUrl, err)
+		}
+		return nil
+	}
+
+	log.Printf("crawling %s", rawCurrUrl)
+	page, err := c.fetch(ctx, rawCurrUrl, prev, delay)
+	if ctx.Err() == nil {
+		if err := c.recordFetch(ctx, normCurrUrl, page, err); err != nil {
+			c.fail(rawCurrUrl, err)
+		}
+	}
+	if len(page.redirects) != 0 {
+		if err := c.recordRedirects(ctx, normCurrUrl, page.redirects); err != nil && ctx.Err() == nil {
+			c.fail(rawCurrUrl, err)
+		}
 	}
+	if errors.Is(err, errRedirectOutOfScope) && c.redirects.mode == redirectRecord {
+		c.skip(normCurrUrl, "redirects out of scope to "+page.redirects[len(page.redirects)-1].to)
+		return nil
+	} else if errors.Is(err, errRedirectDisallowed) { // the same call admit makes for links, so never an error
+		c.skip(normCurrUrl, "redirects to "+page.redirects[len(page.redirects)-1].to+", disallowed by robots.txt")
+		return nil
+	} else if err != nil {
+		if ctx.Err() == nil { // cancellation is not a crawl error
+			c.fail(rawCurrUrl, err)
+		}
+		return nil
+	}
+	if len(page.redirects) != 0 { // the page lives at the end of the chain, so store and resolve links there
+		finalUrl, err := c.canon.canonicalize(page.finalUrl)
+		if err != nil {
+			c.fail(rawCurrUrl, err)
+			return nil
+		}
+		if finalUrl != normCurrUrl && !c.claimRedirect(finalUrl) { // the target is crawled on its own
+			return nil
+		}
+		rawCurrUrl, normCurrUrl = page.finalUrl, finalUrl
+	}
+	if page.notModified { // nothing new to parse, bump the stored row and follow the links it had last time
+		log.Printf("unchanged %s", rawCurrUrl)
+		key, err := c.storeKey(ctx, normCurrUrl)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.fail(rawCurrUrl, err)
+			}
+			return nil
+		}
+		if err := c.db.TouchData(ctx, key); err != nil && ctx.Err() == nil {
+			c.fail(rawCurrUrl, err)
+		}
+		if err := c.storedLinks(ctx, normCurrUrl, key); err != nil && ctx.Err() == nil {
+			c.fail(rawCurrUrl, err)
+		}
+	} else if err := c.dataFromHTML(ctx, rawCurrUrl, normCurrUrl, page, depth); err != nil {
+		if ctx.Err() == nil {
+			c.fail(rawCurrUrl, err)
+		}
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.links[normCurrUrl])
 }
@@ -1,41 +1,224 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
 	"errors"
 	"io"
+	"log"
+	"mime"
 	"net/http"
-	"net/url"
 	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding/unicode"
+)
+
+const (
+	crawlerName = "rumbling" // product token used when the user agent doesn't start with one
+	userAgent   = "rumbling/1.0 (+https://github.com/junwei890/rumbling)"
+)
+
+type validators struct { // from a previous crawl, sent back so unchanged pages cost a 304
+	etag         string
+	lastModified string
+}
+
+const (
+	redirectRefuse = "refuse" // redirects leaving the crawl scope fail the page
+	redirectRecord = "record" // redirects leaving the crawl scope are recorded and the page skipped
+)
+
+const (
+	bodyTruncate = "truncate" // oversized pages keep their first maxBytes
+	bodyReject   = "reject"   // oversized pages fail
 )
 
-func getHTML(rawUrl string) (string, error) {
-	client := &http.Client{}
-	res, err := client.Get(rawUrl)
+var (
+	errTooManyRedirects   = errors.New("too many redirects")
+	errRedirectOutOfScope = errors.New("redirect leaves crawl scope")
+	errRedirectDisallowed = errors.New("redirect target disallowed by robots.txt")
+	errBodyTooLarge       = errors.New("response body too large")
+	errNotHTML            = errors.New("content type not html")
+)
+
+type redirectConfig struct {
+	limit int // most hops followed per page
+	mode  string
+}
+
+type bodyConfig struct {
+	maxBytes int64 // 0 disables the limit
+	mode     string
+}
+
+type redirectHop struct {
+	from   string
+	to     string
+	status int
+}
+
+type fetchedPage struct {
+	body        string // decoded to utf-8
+	charset     string // what the body was decoded from
+	validators  validators
+	notModified bool
+	status      int           // of the last response, 0 when none arrived
+	finalUrl    string        // where the redirects ended, the request url when there were none
+	redirects   []redirectHop // also set when following them failed
+	robotsTag   []string      // X-Robots-Tag header values
+}
+
+func getHTML(ctx context.Context, f fetcher, rawUrl string, prev validators, redirects redirectConfig, follow func(string) error, limits bodyConfig) (fetchedPage, error) {
+	conditional := http.Header{}
+	if prev.etag != "" {
+		conditional.Set("If-None-Match", prev.etag)
+	}
+	if prev.lastModified != "" {
+		conditional.Set("If-Modified-Since", prev.lastModified)
+	}
+
+	res, hops, err := followRedirects(ctx, f, rawUrl, conditional, redirects, follow)
 	if err != nil {
-		return "", err
+		return fetchedPage{redirects: hops}, networkError(err)
 	}
 	defer res.Body.Close()
 
-	if res.StatusCode == 404 {
-		return "", errors.New("dead link")
-	} else if 400 <= res.StatusCode && res.StatusCode < 500 {
-		return "", errors.New("client error")
+	finalUrl := rawUrl
+	if len(hops) != 0 {
+		finalUrl = hops[len(hops)-1].to
+	}
+	if res.StatusCode == http.StatusNotModified {
+		return fetchedPage{
+			validators:  prev,
+			notModified: true,
+			status:      res.StatusCode,
+			finalUrl:    finalUrl,
+			redirects:   hops,
+		}, nil
+	} else if err := statusError(res, time.Now()); err != nil {
+		return fetchedPage{status: res.StatusCode, redirects: hops}, err
 	} else if header := res.Header.Get("Content-Type"); !strings.Contains(header, "text/html") {
-		return "", errors.New("content type not html")
+		return fetchedPage{status: res.StatusCode, redirects: hops}, errNotHTML
 	}
 
-	resData, err := io.ReadAll(res.Body)
+	body, name, truncated, err := readBody(res, limits)
 	if err != nil {
-		return "", err
+		return fetchedPage{status: res.StatusCode, redirects: hops}, err
 	}
-	return string(resData), nil
+	if truncated {
+		log.Printf("truncated %s to %d bytes", finalUrl, limits.maxBytes)
+	}
+	return fetchedPage{
+		body:    body,
+		charset: name,
+		validators: validators{
+			etag:         res.Header.Get("ETag"),
+			lastModified: res.Header.Get("Last-Modified"),
+		},
+		status:    res.StatusCode,
+		finalUrl:  finalUrl,
+		robotsTag: res.Header.Values("X-Robots-Tag"),
+		redirects: hops,
+	}, nil
 }
 
-func normalizeURL(rawUrl string) (string, error) {
-	urlStruct, err := url.Parse(rawUrl)
+func readBody(res *http.Response, limits bodyConfig) (string, string, bool, error) { // returns the utf-8 body, its original charset and whether it was cut short
+	if limits.maxBytes > 0 && limits.mode == bodyReject && res.ContentLength > limits.maxBytes { // no need to download it
+		return "", "", false, errBodyTooLarge
+	}
+
+	reader := io.Reader(res.Body)
+	if limits.maxBytes > 0 {
+		reader = io.LimitReader(res.Body, limits.maxBytes+1) // one extra byte tells us the body was longer
+	}
+	raw, err := io.ReadAll(reader)
+	if err != nil {
+		return "", "", false, networkError(err)
+	}
+	truncated := false
+	if limits.maxBytes > 0 && int64(len(raw)) > limits.maxBytes {
+		if limits.mode == bodyReject {
+			return "", "", false, errBodyTooLarge
+		}
+		raw = raw[:limits.maxBytes]
+		truncated = true
+	}
+
+	encoding, name, certain := charset.DetermineEncoding(raw, res.Header.Get("Content-Type")) // header first, then a bom or <meta charset> in the first 1024 bytes
+	// with no declaration at all, the windows-1252 fallback would mangle utf-8 and mislabel plain ascii
+	if !certain && !metaCharset(raw) && validUTF8(raw, truncated) {
+		encoding, name = unicode.UTF8, "utf-8"
+	}
+	decoded, err := encoding.NewDecoder().Bytes(raw)
 	if err != nil {
-		return "", err
+		return "", "", false, err
 	}
+	return string(decoded), name, truncated, nil
+}
 
-	return urlStruct.Host + strings.TrimRight(urlStruct.Path, "/"), nil
+func metaCharset(raw []byte) bool { // whether a <meta> in the first 1024 bytes names a known charset, as DetermineEncoding's prescan would find
+	if len(raw) > 1024 {
+		raw = raw[:1024]
+	}
+	tokenizer := html.NewTokenizer(bytes.NewReader(raw))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return false
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, more := tokenizer.TagName()
+			if string(name) != "meta" {
+				continue
+			}
+			label, content, contentType := "", "", false
+			for more {
+				var key, value []byte
+				key, value, more = tokenizer.TagAttr()
+				switch string(key) {
+				case "charset":
+					label = string(value)
+				case "content":
+					content = string(value)
+				case "http-equiv":
+					contentType = strings.EqualFold(string(value), "content-type")
+				}
+			}
+			if label == "" && contentType {
+				if _, params, err := mime.ParseMediaType(content); err == nil {
+					label = params["charset"]
+				}
+			}
+			if encoding, _ := charset.Lookup(label); encoding != nil {
+				return true
+			}
+		}
+	}
+}
+
+func validUTF8(raw []byte, truncated bool) bool { // a truncated body may end partway through a character
+	if utf8.Valid(raw) {
+		return true
+	}
+	for cut := 1; truncated && cut < utf8.UTFMax && cut <= len(raw); cut++ {
+		if utf8.Valid(raw[:len(raw)-cut]) {
+			return true
+		}
+	}
+	return false
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{
+		String: s,
+		Valid:  s != "",
+	}
+}
+
+func normalizeURL(rawUrl string) (string, error) {
+	return defaultCanonicalizer.canonicalize(rawUrl)
 }
@@ -1,7 +1,24 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/junwei890/rumbling/internal/database"
 )
 
 func TestNormalizeURL(t *testing.T) {
@@ -13,32 +30,32 @@ func TestNormalizeURL(t *testing.T) {
 		{
 			name:     "test case 1",
 			url:      "http://www.hello.com/world",
-			expected: "www.hello.com/world",
+			expected: "http://www.hello.com/world",
 		},
 		{
 			name:     "test case 2",
 			url:      "http://www.hello.com/world/",
-			expected: "www.hello.com/world",
+			expected: "http://www.hello.com/world",
 		},
 		{
 			name:     "test case 3",
 			url:      "https://www.hello.com/world",
-			expected: "www.hello.com/world",
+			expected: "https://www.hello.com/world",
 		},
 		{
 			name:     "test case 4",
 			url:      "https://www.hello.com/world/",
-			expected: "www.hello.com/world",
+			expected: "https://www.hello.com/world",
 		},
 		{
 			name:     "test case 5",
 			url:      "https://www.hello.com/world?unit=testing",
-			expected: "www.hello.com/world",
+			expected: "https://www.hello.com/world?unit=testing",
 		},
 		{
 			name:     "test case 6",
 			url:      "https://www.hello.com/world?unit=testing#foo",
-			expected: "www.hello.com/world",
+			expected: "https://www.hello.com/world?unit=testing",
 		},
 	}
 
@@ -54,3 +71,1360 @@ func TestNormalizeURL(t *testing.T) {
 		})
 	}
 }
+
+type fakeExec struct {
+	query string
+	args  []driver.Value
+}
+
+type fakeDB struct { // records writes, queries come back empty unless answer is set
+	mu     *sync.Mutex
+	execs  []fakeExec
+	answer func(name string, args []driver.Value) [][]driver.Value // rows for the sqlc query of that name
+}
+
+type fakeConnector struct{ db *fakeDB }
+type fakeDriver struct{ db *fakeDB }
+type fakeConn struct{ db *fakeDB }
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+type fakeRows struct {
+	rows [][]driver.Value
+}
+type fakeTx struct{ db *fakeDB } // commits and rollbacks are recorded as execs of their own
+
+func (f fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(f), nil }
+func (f fakeConnector) Driver() driver.Driver                        { return fakeDriver(f) }
+func (f fakeDriver) Open(string) (driver.Conn, error)                { return fakeConn(f), nil }
+func (f fakeConn) Prepare(query string) (driver.Stmt, error)         { return fakeStmt{f.db, query}, nil }
+func (f fakeConn) Close() error                                      { return nil }
+func (f fakeConn) Begin() (driver.Tx, error)                         { return fakeTx(f), nil }
+func (f fakeTx) Commit() error                                       { return fakeStmt{f.db, "COMMIT"}.exec(nil) }
+func (f fakeTx) Rollback() error                                     { return fakeStmt{f.db, "ROLLBACK"}.exec(nil) }
+func (f fakeStmt) Close() error                                      { return nil }
+func (f fakeStmt) NumInput() int                                     { return -1 }
+func (f *fakeRows) Close() error                                     { return nil }
+
+var fakeQueryName = regexp.MustCompile(`-- name: (\w+)`)
+
+func (f fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows := &fakeRows{}
+	if match := fakeQueryName.FindStringSubmatch(f.query); match != nil && f.db.answer != nil {
+		rows.rows = f.db.answer(match[1], args)
+	}
+	return rows, nil
+}
+
+func (f *fakeRows) Columns() []string {
+	if len(f.rows) == 0 {
+		return nil
+	}
+	return make([]string, len(f.rows[0]))
+}
+
+func (f *fakeRows) Next(dest []driver.Value) error {
+	if len(f.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, f.rows[0])
+	f.rows = f.rows[1:]
+	return nil
+}
+
+func (f fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), f.exec(args)
+}
+
+func (f fakeStmt) exec(args []driver.Value) error {
+	f.db.mu.Lock()
+	defer f.db.mu.Unlock()
+	f.db.execs = append(f.db.execs, fakeExec{query: f.query, args: args})
+	return nil
+}
+
+type fakeRow map[string]driver.Value // column to the value written
+
+var (
+	fakeStatement = regexp.MustCompile(`^\s*(?:-- name: \w+ :\w+\s+)?(INSERT INTO|DELETE FROM|UPDATE) (\w+)`)
+	fakeColumns   = regexp.MustCompile(`^\s*(?:-- name: \w+ :\w+\s+)?INSERT INTO \w+ \(([^)]*)\)`)
+	fakeAssigned  = regexp.MustCompile(`(\w+) = \?`)
+)
+
+func (f *fakeDB) written(statement, table string) []fakeRow { // binds each write's args to the columns its query names, in order
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rows := []fakeRow{}
+	for _, exec := range f.execs {
+		match := fakeStatement.FindStringSubmatch(exec.query)
+		if match == nil || match[1] != statement || match[2] != table {
+			continue
+		}
+		columns := []string{}
+		if insert := fakeColumns.FindStringSubmatch(exec.query); insert != nil { // placeholders come before any datetime('now')
+			for _, column := range strings.Split(insert[1], ",") {
+				columns = append(columns, strings.TrimSpace(column))
+			}
+		} else {
+			for _, assigned := range fakeAssigned.FindAllStringSubmatch(exec.query, -1) {
+				columns = append(columns, assigned[1])
+			}
+		}
+		row := fakeRow{}
+		for i, arg := range exec.args {
+			if _, ok := row[columns[i]]; ok { // set and matched on, like UPDATE data SET url = ? WHERE url = ?
+				row["where."+columns[i]] = arg
+			} else {
+				row[columns[i]] = arg
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func (f *fakeDB) inserted() map[string]int64 { // stored url to depth
+	res := make(map[string]int64)
+	for _, row := range f.written("INSERT INTO", "data") {
+		res[row["url"].(string)] = row["depth"].(int64)
+	}
+	return res
+}
+
+func testCrawler(t testing.TB, db *fakeDB, rawDomain string, opts crawlOptions) *crawlerConfig {
+	dom, err := url.Parse(rawDomain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testFetcher := newHTTPFetcher(fetcherConfig{
+		userAgent:    userAgent,
+		totalTimeout: 10 * time.Second,
+	})
+	conn := sql.OpenDB(fakeConnector{db})
+	return &crawlerConfig{
+		db:    database.New(conn),
+		conn:  conn,
+		links: make(map[string][]string),
+		robots: &robotsCache{
+			mu:      &sync.Mutex{},
+			entries: make(map[string]*robotsEntry),
+			fetcher: testFetcher,
+			agent:   crawlerName,
+		},
+		limiter: &hostLimiter{
+			mu:      &sync.Mutex{},
+			buckets: make(map[string]*tokenBucket),
+		},
+		skipped:      make(map[string]string),
+		decided:      make(map[string]struct{}),
+		roots:        make(map[string]chan struct{}),
+		domain:       dom,
+		mu:           &sync.Mutex{},
+		wg:           &sync.WaitGroup{},
+		maxVisits:    opts.MaxPages,
+		frontierSize: 10000,
+		opts:         opts,
+		canon:        defaultCanonicalizer,
+		directives:   directivesConfig{agent: crawlerName},
+		redirects: redirectConfig{
+			limit: 10,
+			mode:  redirectRefuse,
+		},
+		retries: retryConfig{
+			attempts: 2,
+			base:     time.Millisecond,
+			max:      10 * time.Millisecond,
+		},
+		body: bodyConfig{
+			maxBytes: 1 << 20,
+			mode:     bodyTruncate,
+		},
+		fetcher: testFetcher,
+	}
+}
+
+func siteHandler(links func(path string) []string) http.Handler { // serves a synthetic site, robots.txt and sitemaps 404
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/robots.txt" || req.URL.Path == "/sitemap.xml" {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><body><p>page %s</p>", req.URL.Path)
+		for _, link := range links(req.URL.Path) {
+			fmt.Fprintf(w, `<a href="%s">link</a>`, link)
+		}
+		fmt.Fprint(w, "</body></html>")
+	})
+}
+
+func TestInitCrawl(t *testing.T) {
+	server := httptest.NewServer(siteHandler(func(path string) []string {
+		if path == "/" {
+			return []string{"/a", "/b", "/c", "/d"}
+		}
+		return []string{path + "/1", path + "/2", path + "/3"}
+	}))
+	defer server.Close()
+	host := server.URL
+
+	testCases := []struct {
+		name     string
+		opts     crawlOptions
+		expected map[string]int64
+	}{
+		{
+			name: "test case 1",
+			opts: crawlOptions{MaxPages: 5, MaxDepth: intPtr(5), Concurrency: 1},
+			expected: map[string]int64{
+				host: 0, host + "/a": 1, host + "/b": 1, host + "/c": 1, host + "/d": 1,
+			},
+		},
+		{
+			name: "test case 2",
+			opts: crawlOptions{MaxPages: 100, MaxDepth: intPtr(1), Concurrency: 4},
+			expected: map[string]int64{
+				host: 0, host + "/a": 1, host + "/b": 1, host + "/c": 1, host + "/d": 1,
+			},
+		},
+		{
+			name: "test case 3",
+			opts: crawlOptions{MaxPages: 7, MaxDepth: intPtr(5), Concurrency: 1},
+			expected: map[string]int64{
+				host: 0, host + "/a": 1, host + "/b": 1, host + "/c": 1, host + "/d": 1, host + "/a/1": 2, host + "/a/2": 2,
+			},
+		},
+		{
+			name: "test case 4",
+			opts: crawlOptions{MaxPages: 7, MaxDepth: intPtr(0), Concurrency: 2},
+			expected: map[string]int64{
+				host: 0,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			db := &fakeDB{mu: &sync.Mutex{}}
+			crawler := testCrawler(t, db, server.URL, testCase.opts)
+			crawler.initCrawl(context.Background(), server.URL)
+			if result := db.inserted(); !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestCrawlOrder(t *testing.T) {
+	var mu sync.Mutex
+	visited := []string{}
+	site := siteHandler(func(path string) []string {
+		switch path {
+		case "/":
+			return []string{"/a", "/b"}
+		case "/a":
+			return []string{"/a/1", "/b"} // /b is already queued one level up
+		case "/b":
+			return []string{"/b/1"}
+		case "/a/1":
+			return []string{"/a/1/x"}
+		}
+		return nil
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/robots.txt" && req.URL.Path != "/sitemap.xml" {
+			mu.Lock()
+			visited = append(visited, req.URL.Path)
+			mu.Unlock()
+		}
+		site.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+	host := server.URL
+
+	testCases := []struct {
+		name           string
+		maxDepth       int
+		expectedOrder  []string
+		expectedDepths map[string]int64
+	}{
+		{
+			name:          "test case 1",
+			maxDepth:      5,
+			expectedOrder: []string{"/", "/a", "/b", "/a/1", "/b/1", "/a/1/x"},
+			expectedDepths: map[string]int64{
+				host: 0, host + "/a": 1, host + "/b": 1, host + "/a/1": 2, host + "/b/1": 2, host + "/a/1/x": 3,
+			},
+		},
+		{
+			name:          "test case 2",
+			maxDepth:      2,
+			expectedOrder: []string{"/", "/a", "/b", "/a/1", "/b/1"},
+			expectedDepths: map[string]int64{
+				host: 0, host + "/a": 1, host + "/b": 1, host + "/a/1": 2, host + "/b/1": 2,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mu.Lock()
+			visited = []string{}
+			mu.Unlock()
+			db := &fakeDB{mu: &sync.Mutex{}}
+			crawler := testCrawler(t, db, server.URL, crawlOptions{MaxPages: 20, MaxDepth: intPtr(testCase.maxDepth), Concurrency: 1})
+			crawler.initCrawl(context.Background(), server.URL)
+			mu.Lock()
+			defer mu.Unlock()
+			if !reflect.DeepEqual(visited, testCase.expectedOrder) {
+				t.Errorf("%s failed, %v != %v", testCase.name, visited, testCase.expectedOrder)
+			} else if result := db.inserted(); !reflect.DeepEqual(result, testCase.expectedDepths) {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expectedDepths)
+			}
+		})
+	}
+}
+
+func TestCrawlNotModified(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/":
+			if req.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p>home</p>`)
+		case "/a":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p>a</p>`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+	host := server.URL
+
+	db := &fakeDB{
+		mu: &sync.Mutex{},
+		answer: func(name string, args []driver.Value) [][]driver.Value { // the home page was stored by an earlier crawl
+			if len(args) == 0 || args[0] != host {
+				return nil
+			}
+			switch name {
+			case "GetValidators":
+				return [][]driver.Value{{`"v1"`, nil}}
+			case "ListOutboundLinks":
+				return [][]driver.Value{{host + "/a", "a", "", "earlier"}}
+			}
+			return nil
+		},
+	}
+	crawler := testCrawler(t, db, server.URL, crawlOptions{MaxPages: 10, MaxDepth: intPtr(2), Concurrency: 1})
+	crawler.initCrawl(context.Background(), server.URL)
+
+	if result, expected := db.inserted(), map[string]int64{host + "/a": 1}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("not modified failed, %v != %v", result, expected)
+	}
+	touched := []driver.Value{}
+	for _, row := range db.written("UPDATE", "data") {
+		touched = append(touched, row["url"])
+	}
+	if expected := []driver.Value{host}; !reflect.DeepEqual(touched, expected) {
+		t.Errorf("not modified failed, %v != %v", touched, expected)
+	}
+}
+
+func TestCrawlNotModifiedBrokenLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/" && req.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		http.NotFound(w, req)
+	}))
+	defer server.Close()
+	host := server.URL
+
+	links := []fakeRow{{"crawl_id": "earlier", "source_url": host, "target_url": host + "/missing"}} // stored by the crawl that last saw the page change
+	db := &fakeDB{
+		mu: &sync.Mutex{},
+		answer: func(name string, args []driver.Value) [][]driver.Value {
+			if len(args) == 0 || args[0] != host {
+				return nil
+			}
+			switch name {
+			case "GetValidators":
+				return [][]driver.Value{{`"v1"`, nil}}
+			case "ListOutboundLinks":
+				return [][]driver.Value{{host + "/missing", "missing", "", "earlier"}}
+			}
+			return nil
+		},
+	}
+	crawler := testCrawler(t, db, server.URL, crawlOptions{MaxPages: 10, MaxDepth: intPtr(1), Concurrency: 1})
+	crawler.crawlID = "job"
+	crawler.initCrawl(context.Background(), server.URL)
+
+	for _, row := range db.written("UPDATE", "links") {
+		for _, link := range links {
+			if link["source_url"] == row["source_url"] {
+				link["crawl_id"] = row["crawl_id"]
+			}
+		}
+	}
+	report := [][]driver.Value{} // what ListBrokenLinks joins for this crawl
+	for _, fetch := range db.written("INSERT INTO", "fetches") {
+		if fetch["crawl_id"] != "job" || fetch["broken"] != true {
+			continue
+		}
+		for _, link := range links {
+			if link["target_url"] == fetch["url"] && link["crawl_id"] == fetch["crawl_id"] {
+				report = append(report, []driver.Value{fetch["url"], link["source_url"]})
+			}
+		}
+	}
+	if expected := [][]driver.Value{{host + "/missing", host}}; !reflect.DeepEqual(report, expected) {
+		t.Errorf("not modified failed, %v != %v", report, expected)
+	}
+}
+
+func TestCrawlAllowedHostCanonical(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/b" {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<link rel="canonical" href="/c"><p>b</p>`)
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/" {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<link rel="canonical" href="%s/c"><p>home</p><a href="%s/b">b</a>`, other.URL, other.URL)
+	}))
+	defer server.Close()
+
+	db := &fakeDB{mu: &sync.Mutex{}}
+	crawler := testCrawler(t, db, server.URL, crawlOptions{MaxPages: 10, MaxDepth: intPtr(1), Concurrency: 1, AllowedHosts: []string{strings.TrimPrefix(other.URL, "http://")}})
+	crawler.initCrawl(context.Background(), server.URL)
+
+	// the seed can't claim a page on another host, the allowed host's page can name its own
+	if result, expected := db.inserted(), map[string]int64{server.URL: 0, other.URL + "/c": 1}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("canonical failed, %v != %v", result, expected)
+	}
+}
+
+func TestSaveSkipped(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p>home</p><a href="/private/a">a</a><a href="/private/b">b</a><a href="/private/a">a again</a>`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+	host := server.URL
+
+	db := &fakeDB{mu: &sync.Mutex{}}
+	crawler := testCrawler(t, db, server.URL, crawlOptions{MaxPages: 10, MaxDepth: intPtr(1), Concurrency: 1})
+	crawler.crawlID = "job"
+	crawler.initCrawl(context.Background(), server.URL)
+	if rows := db.written("INSERT INTO", "skipped_urls"); len(rows) != 0 {
+		t.Errorf("skipped failed, %v written before saving", rows)
+	}
+	for range 2 { // a second save has nothing left to write
+		if err := crawler.saveSkipped(context.Background()); err != nil {
+			t.Fatalf("skipped failed, unexpected error: %v", err)
+		}
+	}
+
+	result := [][]driver.Value{}
+	for _, row := range db.written("INSERT INTO", "skipped_urls") {
+		result = append(result, []driver.Value{row["crawl_id"], row["url"], row["reason"]})
+	}
+	expected := [][]driver.Value{
+		{"job", host + "/private/a", "disallowed by robots.txt"},
+		{"job", host + "/private/b", "disallowed by robots.txt"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("skipped failed, %v != %v", result, expected)
+	}
+}
+
+func TestCrawlCanonicalKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/b":
+			if req.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("ETag", `"v1"`)
+			fmt.Fprint(w, `<link rel="canonical" href="/c"><p>b</p><a href="/d">d</a>`)
+		case "/d":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p>d</p>`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+	host := server.URL
+
+	testCases := []struct {
+		name            string
+		answer          func(name string, args []driver.Value) [][]driver.Value
+		expectedAliases map[driver.Value]driver.Value
+		expectedTouched []driver.Value
+		expected        map[string]int64
+	}{
+		{
+			name:            "test case 1",
+			expectedAliases: map[driver.Value]driver.Value{host + "/b": host + "/c"},
+			expectedTouched: []driver.Value{},
+			expected:        map[string]int64{host + "/c": 0, host + "/d": 1},
+		},
+		{
+			name: "test case 2",
+			answer: func(name string, args []driver.Value) [][]driver.Value { // the first crawl stored /b under /c
+				switch {
+				case name == "GetPageAlias" && args[0] == host+"/b":
+					return [][]driver.Value{{host + "/c"}}
+				case name == "GetValidators" && args[0] == host+"/c":
+					return [][]driver.Value{{`"v1"`, nil}}
+				case name == "ListOutboundLinks" && args[0] == host+"/c":
+					return [][]driver.Value{{host + "/d", "d", "", "earlier"}}
+				}
+				return nil
+			},
+			expectedAliases: map[driver.Value]driver.Value{},
+			expectedTouched: []driver.Value{host + "/c"},
+			expected:        map[string]int64{host + "/d": 1},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			db := &fakeDB{mu: &sync.Mutex{}, answer: testCase.answer}
+			crawler := testCrawler(t, db, server.URL, crawlOptions{MaxPages: 10, MaxDepth: intPtr(2), Concurrency: 1})
+			crawler.initCrawl(context.Background(), host+"/b")
+
+			aliases := map[driver.Value]driver.Value{}
+			for _, row := range db.written("INSERT INTO", "page_aliases") {
+				aliases[row["url"]] = row["store_url"]
+			}
+			touched := []driver.Value{}
+			for _, row := range db.written("UPDATE", "data") {
+				touched = append(touched, row["url"])
+			}
+			if !reflect.DeepEqual(aliases, testCase.expectedAliases) {
+				t.Errorf("%s failed, %v != %v", testCase.name, aliases, testCase.expectedAliases)
+			} else if !reflect.DeepEqual(touched, testCase.expectedTouched) {
+				t.Errorf("%s failed, %v != %v", testCase.name, touched, testCase.expectedTouched)
+			} else if result := db.inserted(); !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestCrawlConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	site := siteHandler(func(path string) []string {
+		if path == "/" {
+			return []string{"/a", "/b", "/c", "/d", "/e", "/f", "/g", "/h"}
+		}
+		return []string{path + "/1", path + "/2"}
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		inFlight++
+		peak = max(peak, inFlight)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond) // long enough for unbounded workers to pile up
+		site.ServeHTTP(w, req)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name        string
+		concurrency int
+	}{
+		{
+			name:        "test case 1",
+			concurrency: 1,
+		},
+		{
+			name:        "test case 2",
+			concurrency: 3,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mu.Lock()
+			peak = 0
+			mu.Unlock()
+			db := &fakeDB{mu: &sync.Mutex{}}
+			crawler := testCrawler(t, db, server.URL, crawlOptions{MaxPages: 20, MaxDepth: intPtr(5), Concurrency: testCase.concurrency})
+			crawler.initCrawl(context.Background(), server.URL)
+			mu.Lock()
+			defer mu.Unlock()
+			if peak > testCase.concurrency {
+				t.Errorf("%s failed, %d > %d", testCase.name, peak, testCase.concurrency)
+			}
+		})
+	}
+}
+
+func BenchmarkCrawl(b *testing.B) {
+	const linksPerPage = 2000
+	server := httptest.NewServer(siteHandler(func(path string) []string {
+		id := 0
+		fmt.Sscanf(path, "/p/%d", &id)
+		links := make([]string, 0, linksPerPage)
+		for i := range linksPerPage {
+			links = append(links, fmt.Sprintf("/p/%d", id*linksPerPage+i+1))
+		}
+		return links
+	}))
+	defer server.Close()
+
+	b.ReportAllocs()
+	peak := 0
+	for b.Loop() {
+		db := &fakeDB{mu: &sync.Mutex{}}
+		crawler := testCrawler(b, db, server.URL, crawlOptions{MaxPages: 200, MaxDepth: intPtr(10), Concurrency: 8})
+
+		done := make(chan struct{})
+		sampled := make(chan int)
+		go func() { // samples the goroutine count while the crawl runs
+			highest := 0
+			for {
+				highest = max(highest, runtime.NumGoroutine())
+				select {
+				case <-done:
+					sampled <- highest
+					return
+				case <-time.After(time.Millisecond):
+				}
+			}
+		}()
+		crawler.initCrawl(context.Background(), server.URL)
+		close(done)
+		peak = max(peak, <-sampled)
+	}
+	b.ReportMetric(float64(peak), "peak-goroutines")
+}
+
+func TestGetHTML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("If-None-Match") == `"v1"` || req.Header.Get("If-Modified-Since") == "Wed, 01 Jan 2025 00:00:00 GMT" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Wed, 01 Jan 2025 00:00:00 GMT")
+		fmt.Fprint(w, "<p>hello</p>")
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name     string
+		prev     validators
+		expected fetchedPage
+	}{
+		{
+			name: "test case 1",
+			prev: validators{},
+			expected: fetchedPage{
+				body:    "<p>hello</p>",
+				charset: "utf-8",
+				validators: validators{
+					etag:         `"v1"`,
+					lastModified: "Wed, 01 Jan 2025 00:00:00 GMT",
+				},
+				status:   http.StatusOK,
+				finalUrl: server.URL,
+			},
+		},
+		{
+			name: "test case 2",
+			prev: validators{etag: `"v1"`},
+			expected: fetchedPage{
+				validators:  validators{etag: `"v1"`},
+				notModified: true,
+				status:      http.StatusNotModified,
+				finalUrl:    server.URL,
+			},
+		},
+		{
+			name: "test case 3",
+			prev: validators{lastModified: "Wed, 01 Jan 2025 00:00:00 GMT"},
+			expected: fetchedPage{
+				validators:  validators{lastModified: "Wed, 01 Jan 2025 00:00:00 GMT"},
+				notModified: true,
+				status:      http.StatusNotModified,
+				finalUrl:    server.URL,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := getHTML(context.Background(), newHTTPFetcher(fetcherConfig{}), server.URL, testCase.prev, redirectConfig{limit: 10}, nil, bodyConfig{})
+			if err != nil {
+				t.Errorf("%s failed, unexpected error: %v", testCase.name, err)
+			} else if comp := reflect.DeepEqual(result, testCase.expected); !comp {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestGetHTMLRedirects(t *testing.T) {
+	offsite := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<p>elsewhere</p>")
+	}))
+	defer offsite.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/old":
+			http.Redirect(w, req, "/new", http.StatusMovedPermanently)
+		case "/chain":
+			http.Redirect(w, req, "/old", http.StatusFound)
+		case "/away":
+			http.Redirect(w, req, offsite.URL+"/", http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<p>here</p>")
+		}
+	}))
+	defer server.Close()
+	inScope := func(rawUrl string) error {
+		if !strings.HasPrefix(rawUrl, server.URL) {
+			return errRedirectOutOfScope
+		}
+		return nil
+	}
+
+	testCases := []struct {
+		name      string
+		path      string
+		limit     int
+		finalUrl  string
+		redirects []redirectHop
+		err       error
+	}{
+		{
+			name:     "test case 1",
+			path:     "/new",
+			limit:    10,
+			finalUrl: server.URL + "/new",
+		},
+		{
+			name:     "test case 2",
+			path:     "/chain",
+			limit:    10,
+			finalUrl: server.URL + "/new",
+			redirects: []redirectHop{
+				{from: server.URL + "/chain", to: server.URL + "/old", status: http.StatusFound},
+				{from: server.URL + "/old", to: server.URL + "/new", status: http.StatusMovedPermanently},
+			},
+		},
+		{
+			name:  "test case 3",
+			path:  "/chain",
+			limit: 1,
+			redirects: []redirectHop{
+				{from: server.URL + "/chain", to: server.URL + "/old", status: http.StatusFound},
+				{from: server.URL + "/old", to: server.URL + "/new", status: http.StatusMovedPermanently},
+			},
+			err: errTooManyRedirects,
+		},
+		{
+			name:  "test case 4",
+			path:  "/away",
+			limit: 10,
+			redirects: []redirectHop{
+				{from: server.URL + "/away", to: offsite.URL + "/", status: http.StatusFound},
+			},
+			err: errRedirectOutOfScope,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result, err := getHTML(context.Background(), newHTTPFetcher(fetcherConfig{}), server.URL+testCase.path, validators{}, redirectConfig{limit: testCase.limit}, inScope, bodyConfig{})
+			if !errors.Is(err, testCase.err) {
+				t.Errorf("%s failed, %v != %v", testCase.name, err, testCase.err)
+			} else if result.finalUrl != testCase.finalUrl {
+				t.Errorf("%s failed, %v != %v", testCase.name, result.finalUrl, testCase.finalUrl)
+			} else if !reflect.DeepEqual(result.redirects, testCase.redirects) {
+				t.Errorf("%s failed, %v != %v", testCase.name, result.redirects, testCase.redirects)
+			}
+		})
+	}
+}
+
+func TestCrawlRedirects(t *testing.T) {
+	offsite := httptest.NewServer(siteHandler(func(path string) []string { return nil }))
+	defer offsite.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p>home</p><a href="/moved">moved</a><a href="/away">away</a><a href="/sneaky">sneaky</a>`)
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+		case "/sneaky":
+			http.Redirect(w, req, "/private/page", http.StatusFound)
+		case "/private/page":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p>private</p>`)
+		case "/moved":
+			http.Redirect(w, req, "/docs/", http.StatusMovedPermanently)
+		case "/away":
+			http.Redirect(w, req, offsite.URL+"/", http.StatusFound)
+		case "/docs/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p>docs</p><a href="intro">intro</a>`) // relative to the final url
+		case "/docs/intro":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p>intro</p>`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+	host := server.URL
+
+	testCases := []struct {
+		name     string
+		mode     string
+		expected map[string]int64
+		errors   int
+		skipped  int
+	}{
+		{
+			name: "test case 1",
+			mode: redirectRefuse,
+			expected: map[string]int64{
+				host: 0, host + "/docs": 1, host + "/docs/intro": 2,
+			},
+			errors:  1,
+			skipped: 1,
+		},
+		{
+			name: "test case 2",
+			mode: redirectRecord,
+			expected: map[string]int64{
+				host: 0, host + "/docs": 1, host + "/docs/intro": 2,
+			},
+			skipped: 2,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			db := &fakeDB{mu: &sync.Mutex{}}
+			crawler := testCrawler(t, db, server.URL, crawlOptions{MaxPages: 10, MaxDepth: intPtr(5), Concurrency: 2})
+			crawler.redirects.mode = testCase.mode
+			crawler.initCrawl(context.Background(), server.URL)
+			if result := db.inserted(); !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			} else if crawler.stats.errors != testCase.errors {
+				t.Errorf("%s failed, %v != %v", testCase.name, crawler.stats.errors, testCase.errors)
+			} else if crawler.stats.skipped != testCase.skipped {
+				t.Errorf("%s failed, %v != %v", testCase.name, crawler.stats.skipped, testCase.skipped)
+			}
+		})
+	}
+}
+
+func TestCrawlScope(t *testing.T) {
+	docs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch req.URL.Path {
+		case "/guide/":
+			fmt.Fprint(w, `<p>guide</p><a href="/guide/setup">setup</a><a href="/api/">api</a>`)
+		case "/guide/setup":
+			fmt.Fprint(w, `<p>setup</p>`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer docs.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch req.URL.Path {
+		case "/":
+			fmt.Fprintf(w, `<p>home</p><a href="%s/guide/">guide</a><a href="/guide/news">news</a><a href="/about">about</a><a href="https://elsewhere.test/">elsewhere</a><a href="https://elsewhere.test/more">more</a>`, docs.URL)
+		case "/guide/news":
+			fmt.Fprint(w, `<p>news</p>`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+	host := server.URL
+	docsHost := strings.TrimPrefix(docs.URL, "http://")
+
+	testCases := []struct {
+		name     string
+		opts     crawlOptions
+		expected map[string]int64
+		decided  int
+		skipped  int
+	}{
+		{
+			name: "test case 1",
+			opts: crawlOptions{MaxPages: 10, MaxDepth: intPtr(5), Concurrency: 2},
+			expected: map[string]int64{
+				host: 0, host + "/guide/news": 1,
+			},
+			decided: 5,
+		},
+		{
+			name: "test case 2",
+			opts: crawlOptions{MaxPages: 10, MaxDepth: intPtr(5), Concurrency: 2, AllowedHosts: []string{docsHost}},
+			expected: map[string]int64{
+				host: 0, host + "/guide/news": 1, docs.URL + "/guide": 1, docs.URL + "/guide/setup": 2,
+			},
+			decided: 7,
+		},
+		{
+			name: "test case 3",
+			opts: crawlOptions{MaxPages: 10, MaxDepth: intPtr(5), Concurrency: 2, AllowedHosts: []string{docsHost}, ExcludeRegex: []string{`/(about|api)/?$`}},
+			expected: map[string]int64{
+				host: 0, host + "/guide/news": 1, docs.URL + "/guide": 1, docs.URL + "/guide/setup": 2,
+			},
+			decided: 7,
+			skipped: 2,
+		},
+		{
+			name:     "test case 4",
+			opts:     crawlOptions{MaxPages: 10, MaxDepth: intPtr(5), Concurrency: 2, PathPrefixes: []string{"/guide/"}},
+			expected: map[string]int64{},
+			decided:  1,
+			skipped:  1, // the seed itself is out of scope
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			db := &fakeDB{mu: &sync.Mutex{}}
+			crawler := testCrawler(t, db, server.URL, testCase.opts)
+			crawler.initCrawl(context.Background(), server.URL)
+			if result := db.inserted(); !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			} else if crawler.stats.skipped != testCase.skipped {
+				t.Errorf("%s failed, %v != %v", testCase.name, crawler.stats.skipped, testCase.skipped)
+			} else if recorded := len(db.written("INSERT INTO", "scope_decisions")); len(crawler.decided) != testCase.decided || recorded != testCase.decided {
+				t.Errorf("%s failed, %v %v != %v", testCase.name, len(crawler.decided), recorded, testCase.decided)
+			}
+		})
+	}
+}
+
+func TestCrawlDirectives(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch req.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<p>home</p><a href="/hidden">hidden</a><a href="/sponsor" rel="nofollow">sponsor</a><a href="/tagged">tagged</a>`)
+		case "/hidden":
+			fmt.Fprint(w, `<meta name="robots" content="noindex"><p>hidden</p><a href="/deep">deep</a>`)
+		case "/tagged":
+			w.Header().Set("X-Robots-Tag", "nofollow")
+			fmt.Fprint(w, `<p>tagged</p><a href="/leaf">leaf</a>`)
+		default:
+			fmt.Fprintf(w, `<p>%s</p>`, req.URL.Path)
+		}
+	}))
+	defer server.Close()
+	host := server.URL
+
+	testCases := []struct {
+		name        string
+		ignoreHosts []string
+		expected    map[string]int64
+	}{
+		{
+			name: "test case 1",
+			expected: map[string]int64{
+				host: 0, host + "/tagged": 1, host + "/deep": 2,
+			},
+		},
+		{
+			name:        "test case 2",
+			ignoreHosts: []string{strings.TrimPrefix(host, "http://")},
+			expected: map[string]int64{
+				host: 0, host + "/hidden": 1, host + "/sponsor": 1, host + "/tagged": 1, host + "/deep": 2, host + "/leaf": 2,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			db := &fakeDB{mu: &sync.Mutex{}}
+			crawler := testCrawler(t, db, server.URL, crawlOptions{MaxPages: 10, MaxDepth: intPtr(5), Concurrency: 2})
+			crawler.directives.ignoreHosts = testCase.ignoreHosts
+			crawler.initCrawl(context.Background(), server.URL)
+			if result := db.inserted(); !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestCrawlRetries(t *testing.T) {
+	var mu sync.Mutex
+	hits := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		hits[req.URL.Path]++
+		hit := hits[req.URL.Path]
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/html")
+		switch req.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<p>home</p><a href="/flaky">flaky</a><a href="/down">down</a><a href="/busy">busy</a>`)
+		case "/flaky":
+			if hit < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				fmt.Fprint(w, "<p>bad gateway</p>")
+				return
+			}
+			fmt.Fprint(w, "<p>flaky</p>")
+		case "/down":
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, "<p>internal server error</p>") // must never be stored
+		case "/busy":
+			if hit < 2 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprint(w, "<p>busy</p>")
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+	host := server.URL
+
+	db := &fakeDB{mu: &sync.Mutex{}}
+	crawler := testCrawler(t, db, server.URL, crawlOptions{MaxPages: 10, MaxDepth: intPtr(1), Concurrency: 2})
+	crawler.initCrawl(context.Background(), server.URL)
+
+	expected := map[string]int64{
+		host: 0, host + "/flaky": 1, host + "/busy": 1,
+	}
+	if result := db.inserted(); !reflect.DeepEqual(result, expected) {
+		t.Errorf("retries failed, %v != %v", result, expected)
+	}
+	if crawler.stats.errors != 1 {
+		t.Errorf("retries failed, %v != %v", crawler.stats.errors, 1)
+	}
+	if hits["/down"] != crawler.retries.attempts+1 {
+		t.Errorf("retries failed, %v != %v", hits["/down"], crawler.retries.attempts+1)
+	}
+}
+
+func TestCrawlCancel(t *testing.T) {
+	blocked := make(chan struct{}, 2)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p>home</p><a href="/a">a</a><a href="/b">b</a>`)
+		case "/a", "/b": // hang until the crawl gives up on them
+			blocked <- struct{}{}
+			select {
+			case <-release:
+			case <-req.Context().Done():
+			}
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	host := server.URL
+
+	db := &fakeDB{mu: &sync.Mutex{}}
+	crawler := testCrawler(t, db, server.URL, crawlOptions{MaxPages: 10, MaxDepth: intPtr(1), Concurrency: 2})
+	ctx, cancel := context.WithCancel(context.Background())
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		crawler.initCrawl(ctx, server.URL)
+	}()
+
+	<-blocked
+	cancel()
+	select {
+	case <-returned:
+	case <-time.After(2 * time.Second):
+		t.Fatal("cancel failed, initCrawl still running")
+	}
+
+	fetched := []string{}
+	for _, row := range db.written("INSERT INTO", "fetches") {
+		fetched = append(fetched, row["url"].(string))
+	}
+	if expected := []string{host}; !reflect.DeepEqual(fetched, expected) { // nothing recorded for the fetches that were cut off
+		t.Errorf("cancel failed, %v != %v", fetched, expected)
+	}
+	if result, expected := db.inserted(), map[string]int64{host: 0}; !reflect.DeepEqual(result, expected) { // what was stored before stays
+		t.Errorf("cancel failed, %v != %v", result, expected)
+	}
+	if crawler.stats.errors != 0 {
+		t.Errorf("cancel failed, %v != %v", crawler.stats.errors, 0)
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	testCases := []struct {
+		name          string
+		contentType   string
+		body          []byte
+		contentLength int64
+		limits        bodyConfig
+		expected      string
+		charset       string
+		truncated     bool
+		err           error
+	}{
+		{
+			name:        "test case 1",
+			contentType: "text/html; charset=iso-8859-1",
+			body:        []byte("<p>caf\xe9</p>"),
+			expected:    "<p>café</p>",
+			charset:     "windows-1252",
+		},
+		{
+			name:        "test case 2",
+			contentType: "text/html",
+			body:        []byte(`<meta charset="shift_jis"><p>` + "\x93\xfa\x96\x7b" + "</p>"),
+			expected:    `<meta charset="shift_jis"><p>日本</p>`,
+			charset:     "shift_jis",
+		},
+		{
+			name:        "test case 3",
+			contentType: "text/html; charset=utf-8",
+			body:        []byte("<p>café</p>"),
+			expected:    "<p>café</p>",
+			charset:     "utf-8",
+		},
+		{
+			name:        "test case 4",
+			contentType: "text/html; charset=utf-8",
+			body:        []byte("<p>hello world</p>"),
+			limits:      bodyConfig{maxBytes: 8, mode: bodyTruncate},
+			expected:    "<p>hello",
+			charset:     "utf-8",
+			truncated:   true,
+		},
+		{
+			name:        "test case 5",
+			contentType: "text/html; charset=utf-8",
+			body:        []byte("<p>hello world</p>"),
+			limits:      bodyConfig{maxBytes: 8, mode: bodyReject},
+			err:         errBodyTooLarge,
+		},
+		{
+			name:          "test case 6",
+			contentType:   "text/html; charset=utf-8",
+			body:          []byte("<p>hello world</p>"),
+			contentLength: 18,
+			limits:        bodyConfig{maxBytes: 8, mode: bodyReject},
+			err:           errBodyTooLarge,
+		},
+		{
+			name:        "test case 7",
+			contentType: "text/html; charset=utf-8",
+			body:        []byte("<p>hello world</p>"),
+			limits:      bodyConfig{maxBytes: 18, mode: bodyReject},
+			expected:    "<p>hello world</p>",
+			charset:     "utf-8",
+		},
+		{
+			name:        "test case 8",
+			contentType: "text/html",
+			body:        []byte("<p>hello world</p>"),
+			expected:    "<p>hello world</p>",
+			charset:     "utf-8",
+		},
+		{
+			name:        "test case 9",
+			contentType: "text/html",
+			body:        []byte("<p>" + strings.Repeat("a", 1024) + "café</p>"),
+			expected:    "<p>" + strings.Repeat("a", 1024) + "café</p>",
+			charset:     "utf-8",
+		},
+		{
+			name:        "test case 10",
+			contentType: "text/html",
+			body:        []byte("<p>café</p>"),
+			limits:      bodyConfig{maxBytes: 7, mode: bodyTruncate},
+			expected:    "<p>caf\ufffd",
+			charset:     "utf-8",
+			truncated:   true,
+		},
+		{
+			name:        "test case 11",
+			contentType: "text/html",
+			body:        []byte(`<meta charset="iso-8859-1"><p>hello</p>`),
+			expected:    `<meta charset="iso-8859-1"><p>hello</p>`,
+			charset:     "windows-1252",
+		},
+		{
+			name:        "test case 12",
+			contentType: "text/html",
+			body:        []byte(`<meta http-equiv="Content-Type" content="text/html; charset=shift_jis"><p>hello</p>`),
+			expected:    `<meta http-equiv="Content-Type" content="text/html; charset=shift_jis"><p>hello</p>`,
+			charset:     "shift_jis",
+		},
+		{
+			name:        "test case 13",
+			contentType: "text/html",
+			body:        []byte(`<meta charset="bogus"><p>hello</p>`),
+			expected:    `<meta charset="bogus"><p>hello</p>`,
+			charset:     "utf-8",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			res := &http.Response{
+				Header:        http.Header{"Content-Type": {testCase.contentType}},
+				Body:          io.NopCloser(strings.NewReader(string(testCase.body))),
+				ContentLength: testCase.contentLength,
+			}
+			result, name, truncated, err := readBody(res, testCase.limits)
+			if !errors.Is(err, testCase.err) {
+				t.Errorf("%s failed, %v != %v", testCase.name, err, testCase.err)
+			} else if result != testCase.expected || name != testCase.charset || truncated != testCase.truncated {
+				t.Errorf("%s failed, %v %v %v != %v %v %v", testCase.name, result, name, truncated, testCase.expected, testCase.charset, testCase.truncated)
+			}
+		})
+	}
+}
+
+func TestStoreLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch req.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<p>home</p><a href="/a?utm_source=x">Page A</a><a href="https://other.com/" rel="nofollow">elsewhere</a><a href="mailto:x@example.com">mail</a>`)
+		case "/a":
+			fmt.Fprint(w, `<p>a</p><a href="/">home</a>`)
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+	host := server.URL
+
+	db := &fakeDB{mu: &sync.Mutex{}}
+	crawler := testCrawler(t, db, server.URL, crawlOptions{MaxPages: 10, MaxDepth: intPtr(2), Concurrency: 1})
+	crawler.crawlID = "job"
+	crawler.initCrawl(context.Background(), server.URL)
+
+	result := [][]driver.Value{}
+	for _, row := range db.written("INSERT INTO", "links") {
+		result = append(result, []driver.Value{row["crawl_id"], row["source_url"], row["target_url"], row["anchor_text"], row["rel"]})
+	}
+	deleted := []driver.Value{}
+	for _, row := range db.written("DELETE FROM", "links") {
+		deleted = append(deleted, row["source_url"])
+	}
+	expected := [][]driver.Value{
+		{"job", host, host + "/a", "Page A", ""},
+		{"job", host, "https://other.com", "elsewhere", "nofollow"},
+		{"job", host + "/a", host, "home", ""},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("links failed, %v != %v", result, expected)
+	}
+	if expectedDeleted := []driver.Value{host, host + "/a"}; !reflect.DeepEqual(deleted, expectedDeleted) {
+		t.Errorf("links failed, %v != %v", deleted, expectedDeleted)
+	}
+
+	statements := []string{} // each page's links are replaced in one transaction
+	inLinks := false         // other writes run in transactions of their own
+	for _, exec := range db.execs {
+		if match := fakeStatement.FindStringSubmatch(exec.query); match != nil && match[2] == "links" {
+			statements = append(statements, match[1])
+			inLinks = true
+		} else if (exec.query == "COMMIT" || exec.query == "ROLLBACK") && inLinks {
+			statements = append(statements, exec.query)
+			inLinks = false
+		}
+	}
+	if expectedStatements := []string{"DELETE FROM", "INSERT INTO", "INSERT INTO", "COMMIT", "DELETE FROM", "INSERT INTO", "COMMIT"}; !reflect.DeepEqual(statements, expectedStatements) {
+		t.Errorf("links failed, %v != %v", statements, expectedStatements)
+	}
+}
+
+func TestRecordFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p>home</p><a href="/missing">missing</a><a href="/broken">broken</a><a href="/file.pdf">pdf</a>`)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/file.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+	host := server.URL
+
+	db := &fakeDB{mu: &sync.Mutex{}}
+	crawler := testCrawler(t, db, server.URL, crawlOptions{MaxPages: 10, MaxDepth: intPtr(1), Concurrency: 1})
+	crawler.crawlID = "job"
+	crawler.initCrawl(context.Background(), server.URL)
+
+	result := make(map[string][]driver.Value) // url to status, class and broken
+	for _, row := range db.written("INSERT INTO", "fetches") {
+		result[row["url"].(string)] = []driver.Value{row["status_code"], row["error_class"], row["broken"]}
+	}
+	expected := map[string][]driver.Value{
+		host:               {int64(http.StatusOK), outcomeOK, false},
+		host + "/missing":  {int64(http.StatusNotFound), outcomeNotFound, true},
+		host + "/broken":   {int64(http.StatusInternalServerError), outcomeServerError, true},
+		host + "/file.pdf": {int64(http.StatusOK), outcomeNotHTML, false},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("fetches failed, %v != %v", result, expected)
+	}
+}
@@ -1,53 +1,285 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/junwei890/rumbling/internal/database"
 )
 
+const (
+	defaultPageListLimit = 50
+	maxPageListLimit     = 1000
+)
+
 type crawlerConfig struct {
-	db        *database.Queries
-	links     map[string][]string
-	domain    *url.URL
-	mu        *sync.Mutex
-	wg        *sync.WaitGroup
-	control   chan struct{}
-	maxVisits int
+	db           *database.Queries
+	conn         *sql.DB // begins transactions for writes that must land together
+	links        map[string][]string
+	skipped      map[string]string                 // url to the reason it was not fetched
+	unsaved      []database.InsertSkippedUrlParams // skips not yet written to the database, guarded by mu
+	robots       *robotsCache
+	limiter      *hostLimiter
+	domain       *url.URL
+	mu           *sync.Mutex
+	wg           *sync.WaitGroup
+	maxVisits    int
+	frontierSize int
+	opts         crawlOptions
+	stats        crawlStats
+	crawlID      string
+	dedup        dedupConfig
+	fingerprints []fingerprint            // canonical pages seen so far, guarded by mu
+	roots        map[string]chan struct{} // scheme and host of every page that reached storage, closed once its stored fingerprints are loaded
+	canon        canonicalizer
+	redirects    redirectConfig
+	retries      retryConfig
+	body         bodyConfig
+	fetcher      fetcher
+	rank         rankConfig
+	scope        scope               // built from the seed and options by initCrawl
+	decided      map[string]struct{} // urls whose scope decision has been recorded, hosts for off-host ones
+	directives   directivesConfig
+	content      contentConfig
 }
 
 func (c *apiConfig) postData(w http.ResponseWriter, req *http.Request) {
 	type reqData struct {
 		Url string `json:"url"`
+		crawlOptions
 	}
 	bytes, err := io.ReadAll(req.Body)
 	if err != nil {
 		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
 	}
 	reqUrl := &reqData{}
 	if err := json.Unmarshal(bytes, reqUrl); err != nil {
 		errorResponseWriter(w, http.StatusBadRequest, err)
+		return
 	}
 
 	dom, err := url.Parse(reqUrl.Url)
 	if err != nil {
 		errorResponseWriter(w, http.StatusBadRequest, err)
+		return
+	}
+	if (dom.Scheme != "http" && dom.Scheme != "https") || dom.Hostname() == "" {
+		errorResponseWriter(w, http.StatusBadRequest, errors.New("url must be an absolute http or https url"))
+		return
+	}
+
+	if err := reqUrl.crawlOptions.validate(c.limits); err != nil {
+		errorResponseWriter(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := newScope(reqUrl.Url, reqUrl.crawlOptions, c.canon); err != nil { // catches allowed hosts the canonicalizer rejects
+		errorResponseWriter(w, http.StatusBadRequest, err)
+		return
+	}
+	options, err := json.Marshal(reqUrl.crawlOptions)
+	if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	id := uuid.NewString()
+	job := crawlJob{
+		id:   id,
+		url:  reqUrl.Url,
+		ctx:  c.registerCrawl(id),
+		opts: reqUrl.crawlOptions,
+	}
+	if err := c.db.CreateCrawl(req.Context(), database.CreateCrawlParams{
+		ID:      job.id,
+		Url:     job.url,
+		Options: string(options),
+	}); err != nil {
+		c.unregisterCrawl(job.id)
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	select {
+	case c.queue <- job:
+	default:
+		c.unregisterCrawl(job.id)
+		err := errors.New("crawl queue full")
+		if dbErr := c.db.FinishCrawl(req.Context(), database.FinishCrawlParams{
+			State: crawlFailed,
+			LastError: sql.NullString{
+				String: err.Error(),
+				Valid:  true,
+			},
+			ID: job.id,
+		}); dbErr != nil {
+			log.Println(dbErr)
+		}
+		errorResponseWriter(w, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	type resData struct {
+		ID    string `json:"id"`
+		State string `json:"state"`
+	}
+	w.Header().Set("Location", "/api/crawls/"+job.id)
+	jsonResponseWriter(w, http.StatusAccepted, resData{
+		ID:    job.id,
+		State: crawlQueued,
+	})
+}
+
+func (c *apiConfig) getPages(w http.ResponseWriter, req *http.Request) {
+	type page struct {
+		Url       string    `json:"url"`
+		Title     string    `json:"title"`
+		Depth     int64     `json:"depth"`
+		PageRank  *float64  `json:"pagerank"` // null until the domain has been ranked
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+	type resData struct {
+		Domain string `json:"domain"`
+		Sort   string `json:"sort"`
+		Pages  []page `json:"pages"`
+	}
+
+	query := req.URL.Query()
+	rawDomain := query.Get("domain")
+	if rawDomain == "" {
+		errorResponseWriter(w, http.StatusBadRequest, errors.New("missing domain"))
+		return
+	}
+	dom, err := url.Parse(rawDomain)
+	if err != nil {
+		errorResponseWriter(w, http.StatusBadRequest, err)
+		return
+	}
+	root, err := c.canon.canonicalize((&url.URL{Scheme: dom.Scheme, Host: dom.Host}).String())
+	if err != nil {
+		errorResponseWriter(w, http.StatusBadRequest, err)
+		return
+	}
+	limit, err := queryInt(query, "limit", defaultPageListLimit)
+	if err != nil || limit < 1 || limit > maxPageListLimit {
+		errorResponseWriter(w, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d", maxPageListLimit))
+		return
 	}
-	crawler := &crawlerConfig{
-		db:        c.db,
-		links:     make(map[string][]string),
-		domain:    dom,
-		mu:        &sync.Mutex{},
-		wg:        &sync.WaitGroup{},
-		control:   make(chan struct{}, 5),
-		maxVisits: 20,
+	offset, err := queryInt(query, "offset", 0)
+	if err != nil || offset < 0 {
+		errorResponseWriter(w, http.StatusBadRequest, errors.New("offset must not be negative"))
+		return
 	}
 
-	crawler.initCrawl(reqUrl.Url)
+	res := resData{
+		Domain: root,
+		Sort:   query.Get("sort"),
+		Pages:  []page{},
+	}
+	if res.Sort == "" {
+		res.Sort = "url"
+	}
+	root, pathPrefix, queryPrefix := domainPatterns(root)
+	switch res.Sort {
+	case "url":
+		rows, err := c.db.ListPagesByUrl(req.Context(), database.ListPagesByUrlParams{
+			Root:        root,
+			PathPrefix:  pathPrefix,
+			QueryPrefix: queryPrefix,
+			Limit:       int64(limit),
+			Offset:      int64(offset),
+		})
+		if err != nil {
+			errorResponseWriter(w, http.StatusInternalServerError, err)
+			return
+		}
+		for _, row := range rows {
+			res.Pages = append(res.Pages, page{
+				Url:       row.Url,
+				Title:     row.Title.String,
+				Depth:     row.Depth,
+				PageRank:  nullFloat(row.Pagerank),
+				UpdatedAt: row.UpdatedAt,
+			})
+		}
+	case "pagerank":
+		rows, err := c.db.ListPagesByPageRank(req.Context(), database.ListPagesByPageRankParams{
+			Root:        root,
+			PathPrefix:  pathPrefix,
+			QueryPrefix: queryPrefix,
+			Limit:       int64(limit),
+			Offset:      int64(offset),
+		})
+		if err != nil {
+			errorResponseWriter(w, http.StatusInternalServerError, err)
+			return
+		}
+		for _, row := range rows {
+			res.Pages = append(res.Pages, page{
+				Url:       row.Url,
+				Title:     row.Title.String,
+				Depth:     row.Depth,
+				PageRank:  nullFloat(row.Pagerank),
+				UpdatedAt: row.UpdatedAt,
+			})
+		}
+	default:
+		errorResponseWriter(w, http.StatusBadRequest, errors.New("sort must be url or pagerank"))
+		return
+	}
+	jsonResponseWriter(w, http.StatusOK, res)
+}
+
+func (c *apiConfig) getPage(w http.ResponseWriter, req *http.Request) {
+	type resData struct {
+		Url         string   `json:"url"`
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		Headings    []string `json:"headings"`
+		Content     string   `json:"content"`      // what keywords are extracted from
+		FullContent *string  `json:"full_content"` // null unless CONTENT_KEEP_FULL was set when the page was crawled
+	}
 
-	w.WriteHeader(http.StatusOK)
+	normUrl, ok := c.linkQueryUrl(w, req)
+	if !ok {
+		return
+	}
+	storeUrl, err := c.db.GetPageAlias(req.Context(), normUrl) // pages naming a rel=canonical are stored under it
+	if errors.Is(err, sql.ErrNoRows) {
+		storeUrl = normUrl
+	} else if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+	row, err := c.db.RetrieveData(req.Context(), storeUrl)
+	if errors.Is(err, sql.ErrNoRows) {
+		errorResponseWriter(w, http.StatusNotFound, errors.New("page not found"))
+		return
+	} else if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := resData{
+		Url:         row.Url,
+		Title:       row.Title.String,
+		Description: row.Description.String,
+		Headings:    []string{},
+		Content:     row.Content,
+		FullContent: nullText(row.FullContent),
+	}
+	if row.Headings.String != "" {
+		res.Headings = strings.Split(row.Headings.String, "\n")
+	}
+	jsonResponseWriter(w, http.StatusOK, res)
 }
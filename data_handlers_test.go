@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestPostData(t *testing.T) {
+	server := httptest.NewServer(siteHandler(func(path string) []string {
+		return nil
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name           string
+		body           string
+		queueSize      int
+		expectedStatus int
+		expectedStates []string // after the handler, then after a worker has run whatever it queued
+	}{
+		{
+			name:           "test case 1",
+			body:           fmt.Sprintf(`{"url": %q, "max_pages": 5}`, server.URL),
+			queueSize:      1,
+			expectedStatus: http.StatusAccepted,
+			expectedStates: []string{crawlRunning, crawlDone},
+		},
+		{
+			name:           "test case 2",
+			body:           fmt.Sprintf(`{"url": %q, "max_pages": 5}`, server.URL),
+			queueSize:      0,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedStates: []string{crawlFailed},
+		},
+		{
+			name:           "test case 3",
+			body:           `{"url": "ftp://example.com"}`,
+			queueSize:      1,
+			expectedStatus: http.StatusBadRequest,
+			expectedStates: []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			db := &fakeDB{mu: &sync.Mutex{}}
+			api := testAPI(db, testCase.queueSize)
+			rec := httptest.NewRecorder()
+			api.postData(rec, httptest.NewRequest(http.MethodPost, "/api/data", strings.NewReader(testCase.body)))
+			if rec.Code != testCase.expectedStatus {
+				t.Fatalf("%s failed, %v != %v", testCase.name, rec.Code, testCase.expectedStatus)
+			}
+
+			created := db.written("INSERT INTO", "crawls")
+			id := ""
+			if len(created) == 1 {
+				id = created[0]["id"].(string)
+			}
+			if rec.Code == http.StatusAccepted {
+				res := struct {
+					ID    string `json:"id"`
+					State string `json:"state"`
+				}{}
+				if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+					t.Fatalf("%s failed, unexpected error: %v", testCase.name, err)
+				}
+				if res.ID != id || res.State != crawlQueued {
+					t.Errorf("%s failed, %v != %v", testCase.name, res, []string{id, crawlQueued})
+				}
+				if location := rec.Header().Get("Location"); location != "/api/crawls/"+id {
+					t.Errorf("%s failed, %v != %v", testCase.name, location, "/api/crawls/"+id)
+				}
+				close(api.queue)
+				api.crawlWorker()
+			}
+
+			if result := db.states(id); !reflect.DeepEqual(result, testCase.expectedStates) {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expectedStates)
+			}
+			if len(api.cancels) != 0 { // every path unregisters the crawl
+				t.Errorf("%s failed, %v != %v", testCase.name, len(api.cancels), 0)
+			}
+		})
+	}
+}
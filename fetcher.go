@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type fetcher interface { // one http round trip, redirects come back as responses so callers can record and scope them
+	do(req *http.Request) (*http.Response, error)
+}
+
+type fetcherConfig struct {
+	userAgent      string
+	headers        http.Header // sent with every request unless the request sets them
+	connectTimeout time.Duration
+	readTimeout    time.Duration // until the response headers arrive
+	totalTimeout   time.Duration // the whole exchange, body included
+	proxy          *url.URL      // nil falls back to HTTP_PROXY and friends
+	tlsInsecure    bool          // for internal sites with self-signed certificates
+	tlsMinVersion  uint16
+	maxIdlePerHost int
+}
+
+type httpFetcher struct { // shared by every crawl so connections to a host are reused
+	client    *http.Client
+	userAgent string
+	headers   http.Header
+}
+
+func newHTTPFetcher(config fetcherConfig) *httpFetcher {
+	proxy := http.ProxyFromEnvironment
+	if config.proxy != nil {
+		proxy = http.ProxyURL(config.proxy)
+	}
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   config.connectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   config.connectTimeout,
+		ResponseHeaderTimeout: config.readTimeout,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: config.tlsInsecure, // off unless the operator opts in
+			MinVersion:         config.tlsMinVersion,
+		},
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: config.maxIdlePerHost,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	return &httpFetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   config.totalTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		userAgent: config.userAgent,
+		headers:   config.headers,
+	}
+}
+
+func (f *httpFetcher) do(req *http.Request) (*http.Response, error) {
+	for key, values := range f.headers {
+		if req.Header.Get(key) == "" {
+			req.Header[key] = values
+		}
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", f.userAgent)
+	}
+	return f.client.Do(req)
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+func followRedirects(ctx context.Context, f fetcher, rawUrl string, header http.Header, redirects redirectConfig, follow func(string) error) (*http.Response, []redirectHop, error) { // follow vets every hop, nil follows redirects anywhere
+	var hops []redirectHop
+	current := rawUrl
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, current, nil)
+		if err != nil {
+			return nil, hops, err
+		}
+		maps.Copy(req.Header, header)
+
+		res, err := f.do(req)
+		if err != nil {
+			return nil, hops, err
+		}
+		location := res.Header.Get("Location")
+		if !isRedirect(res.StatusCode) || location == "" {
+			return res, hops, nil
+		}
+		io.Copy(io.Discard, io.LimitReader(res.Body, 4096)) // lets the connection be reused
+		res.Body.Close()
+
+		next, err := req.URL.Parse(location)
+		if err != nil {
+			return nil, hops, err
+		}
+		hops = append(hops, redirectHop{
+			from:   current,
+			to:     next.String(),
+			status: res.StatusCode,
+		})
+		if len(hops) > redirects.limit {
+			return nil, hops, errTooManyRedirects
+		}
+		if follow != nil {
+			if err := follow(next.String()); err != nil {
+				return nil, hops, err
+			}
+		}
+		current = next.String()
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/moved":
+			http.Redirect(w, req, "/", http.StatusFound)
+			return
+		}
+		w.Header().Set("X-User-Agent", req.Header.Get("User-Agent"))
+		w.Header().Set("X-Team", req.Header.Get("X-Team"))
+	}))
+	defer server.Close()
+
+	f := newHTTPFetcher(fetcherConfig{
+		userAgent:   "test-agent",
+		headers:     http.Header{"X-Team": {"search"}},
+		readTimeout: 50 * time.Millisecond,
+	})
+
+	testCases := []struct {
+		name      string
+		path      string
+		header    http.Header
+		status    int
+		userAgent string
+		team      string
+		err       bool
+	}{
+		{
+			name:      "test case 1",
+			path:      "/",
+			status:    http.StatusOK,
+			userAgent: "test-agent",
+			team:      "search",
+		},
+		{
+			name:      "test case 2",
+			path:      "/",
+			header:    http.Header{"User-Agent": {"other"}, "X-Team": {"crawl"}},
+			status:    http.StatusOK,
+			userAgent: "other",
+			team:      "crawl",
+		},
+		{
+			name:   "test case 3",
+			path:   "/moved",
+			status: http.StatusFound,
+		},
+		{
+			name: "test case 4",
+			path: "/slow",
+			err:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, server.URL+testCase.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, values := range testCase.header {
+				req.Header[key] = values
+			}
+			res, err := f.do(req)
+			if testCase.err {
+				if err == nil {
+					res.Body.Close()
+					t.Errorf("%s failed, expected an error", testCase.name)
+				}
+				return
+			} else if err != nil {
+				t.Errorf("%s failed, unexpected error: %v", testCase.name, err)
+				return
+			}
+			defer res.Body.Close()
+			if res.StatusCode != testCase.status {
+				t.Errorf("%s failed, %v != %v", testCase.name, res.StatusCode, testCase.status)
+			} else if result := res.Header.Get("X-User-Agent"); result != testCase.userAgent {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.userAgent)
+			} else if result := res.Header.Get("X-Team"); result != testCase.team {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.team)
+			}
+		})
+	}
+}
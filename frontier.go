@@ -0,0 +1,82 @@
+package main
+
+import (
+	"sync"
+)
+
+type frontierItem struct {
+	rawUrl  string
+	normUrl string
+	depth   int // hops from the seed url
+}
+
+type frontier struct { // bounded fifo of urls waiting to be crawled, fifo keeps the crawl breadth first
+	mu      *sync.Mutex
+	cond    *sync.Cond
+	queue   []frontierItem
+	seen    map[string]struct{} // normalized urls ever queued
+	limit   int
+	pending int // queued plus in progress, the crawl is over when this hits 0
+	closed  bool
+}
+
+func newFrontier(limit int) *frontier {
+	mu := &sync.Mutex{}
+	return &frontier{
+		mu:    mu,
+		cond:  sync.NewCond(mu),
+		seen:  make(map[string]struct{}),
+		limit: limit,
+	}
+}
+
+func (f *frontier) push(item frontierItem) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed || len(f.queue) >= f.limit {
+		return false
+	}
+	if _, ok := f.seen[item.normUrl]; ok {
+		return false
+	}
+	f.seen[item.normUrl] = struct{}{}
+	f.queue = append(f.queue, item)
+	f.pending++
+	f.cond.Signal()
+	return true
+}
+
+func (f *frontier) pop() (frontierItem, bool) { // blocks until there is work, returns false once the crawl is over
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for len(f.queue) == 0 && f.pending != 0 && !f.closed {
+		f.cond.Wait()
+	}
+	if f.closed || len(f.queue) == 0 {
+		return frontierItem{}, false
+	}
+	item := f.queue[0]
+	f.queue[0] = frontierItem{}
+	f.queue = f.queue[1:]
+	return item, true
+}
+
+func (f *frontier) done() { // marks a popped item as finished, after any links it produced were pushed
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pending--
+	if f.pending == 0 {
+		f.cond.Broadcast() // wake idle workers so they can exit
+	}
+}
+
+func (f *frontier) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	f.cond.Broadcast()
+}
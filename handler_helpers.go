@@ -1,19 +1,22 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 )
 
 func errorResponseWriter(w http.ResponseWriter, statusCode int, errMsg error) {
 	log.Println(errMsg)
 
 	type errRes struct {
-		Error error `json:"error"`
+		Error string `json:"error"`
 	}
 	res := errRes{
-		Error: errMsg,
+		Error: errMsg.Error(),
 	}
 	bytes, err := json.Marshal(res)
 	if err != nil {
@@ -26,3 +29,39 @@ func errorResponseWriter(w http.ResponseWriter, statusCode int, errMsg error) {
 		log.Println(err)
 	}
 }
+
+func jsonResponseWriter(w http.ResponseWriter, statusCode int, payload any) {
+	bytes, err := json.Marshal(payload)
+	if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(bytes); err != nil {
+		log.Println(err)
+	}
+}
+
+func queryInt(query url.Values, key string, fallback int) (int, error) {
+	raw := query.Get(key)
+	if raw == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(raw)
+}
+
+func nullFloat(value sql.NullFloat64) *float64 { // null in json rather than a misleading 0
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
+
+func nullText(value sql.NullString) *string { // null in json when the column was never written
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
@@ -7,39 +7,367 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
-const insertData = `-- name: InsertData :exec
-INSERT INTO data (url, content, created_at, updated_at) VALUES (
-	?,
-	?,
-	datetime('now'),
-	datetime('now')
-)
+const deleteData = `-- name: DeleteData :exec
+DELETE FROM data WHERE url = ?
+`
+
+func (q *Queries) DeleteData(ctx context.Context, url string) error {
+	_, err := q.db.ExecContext(ctx, deleteData, url)
+	return err
+}
+
+const getValidators = `-- name: GetValidators :one
+SELECT etag, last_modified FROM data WHERE url = ?
+`
+
+type GetValidatorsRow struct {
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) GetValidators(ctx context.Context, url string) (GetValidatorsRow, error) {
+	row := q.db.QueryRowContext(ctx, getValidators, url)
+	var i GetValidatorsRow
+	err := row.Scan(&i.Etag, &i.LastModified)
+	return i, err
+}
+
+const listDataUrls = `-- name: ListDataUrls :many
+SELECT url FROM data ORDER BY url
+`
+
+func (q *Queries) ListDataUrls(ctx context.Context) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listDataUrls)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		items = append(items, url)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDomainPages = `-- name: ListDomainPages :many
+SELECT url FROM data WHERE url = ? OR url LIKE ? ESCAPE '\' OR url LIKE ? ESCAPE '\'
 `
 
-type InsertDataParams struct {
+type ListDomainPagesParams struct {
+	Root        string
+	PathPrefix  string
+	QueryPrefix string
+}
+
+func (q *Queries) ListDomainPages(ctx context.Context, arg ListDomainPagesParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listDomainPages, arg.Root, arg.PathPrefix, arg.QueryPrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var url string
+		if err := rows.Scan(&url); err != nil {
+			return nil, err
+		}
+		items = append(items, url)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFingerprints = `-- name: ListFingerprints :many
+SELECT url, simhash FROM data WHERE url LIKE ? ESCAPE '\' AND simhash IS NOT NULL AND canonical_url IS NULL
+`
+
+type ListFingerprintsRow struct {
 	Url     string
-	Content string
+	Simhash sql.NullInt64
 }
 
-func (q *Queries) InsertData(ctx context.Context, arg InsertDataParams) error {
-	_, err := q.db.ExecContext(ctx, insertData, arg.Url, arg.Content)
+func (q *Queries) ListFingerprints(ctx context.Context, url string) ([]ListFingerprintsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listFingerprints, url)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFingerprintsRow
+	for rows.Next() {
+		var i ListFingerprintsRow
+		if err := rows.Scan(&i.Url, &i.Simhash); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPagesByPageRank = `-- name: ListPagesByPageRank :many
+SELECT url, title, depth, pagerank, updated_at FROM data
+WHERE url = ? OR url LIKE ? ESCAPE '\' OR url LIKE ? ESCAPE '\'
+ORDER BY pagerank DESC, url
+LIMIT ? OFFSET ?
+`
+
+type ListPagesByPageRankParams struct {
+	Root        string
+	PathPrefix  string
+	QueryPrefix string
+	Limit       int64
+	Offset      int64
+}
+
+type ListPagesByPageRankRow struct {
+	Url       string
+	Title     sql.NullString
+	Depth     int64
+	Pagerank  sql.NullFloat64
+	UpdatedAt time.Time
+}
+
+func (q *Queries) ListPagesByPageRank(ctx context.Context, arg ListPagesByPageRankParams) ([]ListPagesByPageRankRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPagesByPageRank,
+		arg.Root,
+		arg.PathPrefix,
+		arg.QueryPrefix,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPagesByPageRankRow
+	for rows.Next() {
+		var i ListPagesByPageRankRow
+		if err := rows.Scan(
+			&i.Url,
+			&i.Title,
+			&i.Depth,
+			&i.Pagerank,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPagesByUrl = `-- name: ListPagesByUrl :many
+SELECT url, title, depth, pagerank, updated_at FROM data
+WHERE url = ? OR url LIKE ? ESCAPE '\' OR url LIKE ? ESCAPE '\'
+ORDER BY url
+LIMIT ? OFFSET ?
+`
+
+type ListPagesByUrlParams struct {
+	Root        string
+	PathPrefix  string
+	QueryPrefix string
+	Limit       int64
+	Offset      int64
+}
+
+type ListPagesByUrlRow struct {
+	Url       string
+	Title     sql.NullString
+	Depth     int64
+	Pagerank  sql.NullFloat64
+	UpdatedAt time.Time
+}
+
+func (q *Queries) ListPagesByUrl(ctx context.Context, arg ListPagesByUrlParams) ([]ListPagesByUrlRow, error) {
+	rows, err := q.db.QueryContext(ctx, listPagesByUrl,
+		arg.Root,
+		arg.PathPrefix,
+		arg.QueryPrefix,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPagesByUrlRow
+	for rows.Next() {
+		var i ListPagesByUrlRow
+		if err := rows.Scan(
+			&i.Url,
+			&i.Title,
+			&i.Depth,
+			&i.Pagerank,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const renameData = `-- name: RenameData :exec
+UPDATE data SET url = ?, updated_at = datetime('now') WHERE url = ?
+`
+
+type RenameDataParams struct {
+	NewUrl string
+	OldUrl string
+}
+
+func (q *Queries) RenameData(ctx context.Context, arg RenameDataParams) error {
+	_, err := q.db.ExecContext(ctx, renameData, arg.NewUrl, arg.OldUrl)
 	return err
 }
 
 const retrieveData = `-- name: RetrieveData :one
-SELECT url, content FROM data WHERE url=?
+SELECT url, content, full_content, title, description, headings FROM data WHERE url=?
 `
 
 type RetrieveDataRow struct {
-	Url     string
-	Content string
+	Url         string
+	Content     string
+	FullContent sql.NullString
+	Title       sql.NullString
+	Description sql.NullString
+	Headings    sql.NullString
 }
 
 func (q *Queries) RetrieveData(ctx context.Context, url string) (RetrieveDataRow, error) {
 	row := q.db.QueryRowContext(ctx, retrieveData, url)
 	var i RetrieveDataRow
-	err := row.Scan(&i.Url, &i.Content)
+	err := row.Scan(
+		&i.Url,
+		&i.Content,
+		&i.FullContent,
+		&i.Title,
+		&i.Description,
+		&i.Headings,
+	)
 	return i, err
 }
+
+const touchData = `-- name: TouchData :exec
+UPDATE data SET updated_at = datetime('now') WHERE url = ?
+`
+
+func (q *Queries) TouchData(ctx context.Context, url string) error {
+	_, err := q.db.ExecContext(ctx, touchData, url)
+	return err
+}
+
+const updatePageRank = `-- name: UpdatePageRank :exec
+UPDATE data SET pagerank = ? WHERE url = ?
+`
+
+type UpdatePageRankParams struct {
+	Pagerank sql.NullFloat64
+	Url      string
+}
+
+func (q *Queries) UpdatePageRank(ctx context.Context, arg UpdatePageRankParams) error {
+	_, err := q.db.ExecContext(ctx, updatePageRank, arg.Pagerank, arg.Url)
+	return err
+}
+
+const upsertData = `-- name: UpsertData :exec
+INSERT INTO data (url, content, depth, etag, last_modified, simhash, canonical_url, charset, title, description, headings, full_content, created_at, updated_at) VALUES (
+	?,
+	?,
+	?,
+	?,
+	?,
+	?,
+	?,
+	?,
+	?,
+	?,
+	?,
+	?,
+	datetime('now'),
+	datetime('now')
+) ON CONFLICT (url) DO UPDATE SET
+	content = excluded.content,
+	depth = excluded.depth,
+	etag = excluded.etag,
+	last_modified = excluded.last_modified,
+	simhash = excluded.simhash,
+	canonical_url = excluded.canonical_url,
+	charset = excluded.charset,
+	title = excluded.title,
+	description = excluded.description,
+	headings = excluded.headings,
+	full_content = excluded.full_content,
+	updated_at = datetime('now')
+`
+
+type UpsertDataParams struct {
+	Url          string
+	Content      string
+	Depth        int64
+	Etag         sql.NullString
+	LastModified sql.NullString
+	Simhash      sql.NullInt64
+	CanonicalUrl sql.NullString
+	Charset      sql.NullString
+	Title        sql.NullString
+	Description  sql.NullString
+	Headings     sql.NullString
+	FullContent  sql.NullString
+}
+
+func (q *Queries) UpsertData(ctx context.Context, arg UpsertDataParams) error {
+	_, err := q.db.ExecContext(ctx, upsertData,
+		arg.Url,
+		arg.Content,
+		arg.Depth,
+		arg.Etag,
+		arg.LastModified,
+		arg.Simhash,
+		arg.CanonicalUrl,
+		arg.Charset,
+		arg.Title,
+		arg.Description,
+		arg.Headings,
+		arg.FullContent,
+	)
+	return err
+}
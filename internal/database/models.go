@@ -5,13 +5,105 @@
 package database
 
 import (
+	"database/sql"
 	"time"
 )
 
+type Crawl struct {
+	ID           string
+	Url          string
+	State        string
+	PagesVisited int64
+	PagesStored  int64
+	PagesSkipped int64
+	Errors       int64
+	LastError    sql.NullString
+	CreatedAt    time.Time
+	StartedAt    sql.NullTime
+	FinishedAt   sql.NullTime
+	UpdatedAt    time.Time
+	Options      string
+}
+
 type Datum struct {
+	ID           int64
+	Url          string
+	Content      string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Depth        int64
+	Etag         sql.NullString
+	LastModified sql.NullString
+	Simhash      sql.NullInt64
+	CanonicalUrl sql.NullString
+	Charset      sql.NullString
+	Pagerank     sql.NullFloat64
+	Title        sql.NullString
+	Description  sql.NullString
+	Headings     sql.NullString
+	FullContent  sql.NullString
+}
+
+type Duplicate struct {
+	ID           int64
+	CrawlID      string
+	Url          string
+	CanonicalUrl string
+	Distance     int64
+	CreatedAt    time.Time
+}
+
+type Fetch struct {
+	ID         int64
+	CrawlID    string
+	Url        string
+	StatusCode sql.NullInt64
+	ErrorClass string
+	Error      sql.NullString
+	Broken     bool
+	CreatedAt  time.Time
+}
+
+type Link struct {
+	ID         int64
+	CrawlID    string
+	SourceUrl  string
+	TargetUrl  string
+	AnchorText string
+	Rel        string
+	CreatedAt  time.Time
+}
+
+type Redirect struct {
+	ID         int64
+	CrawlID    string
+	Url        string
+	Hop        int64
+	FromUrl    string
+	ToUrl      string
+	StatusCode int64
+	CreatedAt  time.Time
+}
+
+type ScopeDecision struct {
+	ID        int64
+	CrawlID   string
+	Url       string
+	InScope   bool
+	Reason    string
+	CreatedAt time.Time
+}
+
+type SkippedUrl struct {
 	ID        int64
+	CrawlID   string
 	Url       string
-	Content   string
+	Reason    string
 	CreatedAt time.Time
+}
+
+type PageAlias struct {
+	Url       string
+	StoreUrl  string
 	UpdatedAt time.Time
 }
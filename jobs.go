@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/junwei890/rumbling/internal/database"
+)
+
+const (
+	crawlQueued    = "queued"
+	crawlRunning   = "running"
+	crawlDone      = "done"
+	crawlFailed    = "failed"
+	crawlCancelled = "cancelled"
+	crawlTimedOut  = "timed_out" // stopped by timeout_seconds, what was stored is kept
+
+	progressInterval = 2 * time.Second
+)
+
+type crawlJob struct {
+	id   string
+	url  string
+	ctx  context.Context // cancelled by DELETE /api/crawls/{id}
+	opts crawlOptions
+}
+
+type crawlStats struct {
+	visited int
+	stored  int
+	skipped int
+	errors  int
+	lastErr error
+}
+
+func (c *apiConfig) crawlWorker() {
+	for job := range c.queue {
+		c.runCrawl(job)
+	}
+}
+
+func (c *apiConfig) registerCrawl(id string) context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	c.jobsMu.Lock()
+	defer c.jobsMu.Unlock()
+	c.cancels[id] = cancel
+	return ctx
+}
+
+func (c *apiConfig) unregisterCrawl(id string) {
+	c.jobsMu.Lock()
+	defer c.jobsMu.Unlock()
+
+	if cancel, ok := c.cancels[id]; ok {
+		cancel()
+		delete(c.cancels, id)
+	}
+}
+
+func (c *apiConfig) cancelCrawl(id string) bool {
+	c.jobsMu.Lock()
+	defer c.jobsMu.Unlock()
+
+	cancel, ok := c.cancels[id]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+func (c *apiConfig) runCrawl(job crawlJob) {
+	defer c.unregisterCrawl(job.id)
+
+	// job.ctx only stops the crawl, bookkeeping writes still need to happen
+	ctx := context.Background()
+	if job.ctx.Err() != nil { // cancelled while queued
+		if err := c.db.CancelQueuedCrawl(ctx, job.id); err != nil {
+			log.Printf("crawl %s: %v", job.id, err)
+		}
+		return
+	}
+	if err := c.db.StartCrawl(ctx, job.id); err != nil {
+		log.Printf("crawl %s: %v", job.id, err)
+	}
+
+	dom, err := url.Parse(job.url)
+	if err != nil { // validated in postData, should not happen
+		c.finishCrawl(job, crawlFailed, crawlStats{errors: 1, lastErr: err})
+		return
+	}
+	crawler := &crawlerConfig{
+		db:           c.db,
+		conn:         c.conn,
+		links:        make(map[string][]string),
+		skipped:      make(map[string]string),
+		decided:      make(map[string]struct{}),
+		roots:        make(map[string]chan struct{}),
+		robots:       c.robots,
+		limiter:      c.limiter,
+		domain:       dom,
+		mu:           &sync.Mutex{},
+		wg:           &sync.WaitGroup{},
+		maxVisits:    job.opts.MaxPages,
+		opts:         job.opts,
+		frontierSize: c.limits.frontierSize,
+		crawlID:      job.id,
+		dedup:        c.dedup,
+		canon:        c.canon,
+		redirects:    c.redirects,
+		retries:      c.retries,
+		body:         c.body,
+		fetcher:      c.fetcher,
+		rank:         c.rank,
+		directives:   c.directives,
+		content:      c.content,
+	}
+	if c.warc.dir != "" {
+		writer, err := newWarcWriter(c.warc, job.id)
+		if err != nil {
+			c.finishCrawl(job, crawlFailed, crawlStats{errors: 1, lastErr: err})
+			return
+		}
+		defer func() {
+			if err := writer.close(); err != nil {
+				log.Printf("crawl %s: %v", job.id, err)
+			}
+		}()
+		crawler.archiveTo(writer)
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() { // periodically persist progress so the status endpoint has something to show
+		defer close(stopped)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				stats := crawler.snapshot()
+				if err := c.db.UpdateCrawlProgress(ctx, database.UpdateCrawlProgressParams{
+					PagesVisited: int64(stats.visited),
+					PagesStored:  int64(stats.stored),
+					PagesSkipped: int64(stats.skipped),
+					Errors:       int64(stats.errors),
+					ID:           job.id,
+				}); err != nil {
+					log.Printf("crawl %s: %v", job.id, err)
+				}
+				if err := crawler.saveSkipped(ctx); err != nil {
+					log.Printf("crawl %s: %v", job.id, err)
+				}
+			}
+		}
+	}()
+
+	crawlCtx, cancel := context.WithTimeout(job.ctx, job.opts.timeout())
+	defer cancel()
+	crawler.initCrawl(crawlCtx, job.url)
+	close(done)
+	<-stopped
+	if err := crawler.saveSkipped(ctx); err != nil {
+		log.Printf("crawl %s: %v", job.id, err)
+	}
+
+	if c.rank.iterations > 0 {
+		if err := crawler.rankPages(ctx); err != nil {
+			log.Printf("crawl %s: pagerank: %v", job.id, err)
+		}
+	}
+
+	stats := crawler.snapshot()
+	state := crawlDone
+	if job.ctx.Err() != nil { // whatever was stored before cancelling is kept
+		state = crawlCancelled
+	} else if crawlCtx.Err() != nil {
+		state = crawlTimedOut
+		stats.lastErr = errors.New("crawl timed out")
+	} else if stats.stored == 0 && stats.errors != 0 { // nothing made it into the database
+		state = crawlFailed
+	}
+	c.finishCrawl(job, state, stats)
+}
+
+func (c *apiConfig) finishCrawl(job crawlJob, state string, stats crawlStats) {
+	lastErr := sql.NullString{}
+	if stats.lastErr != nil {
+		lastErr = sql.NullString{
+			String: stats.lastErr.Error(),
+			Valid:  true,
+		}
+	}
+
+	if err := c.db.FinishCrawl(context.Background(), database.FinishCrawlParams{
+		State:        state,
+		PagesVisited: int64(stats.visited),
+		PagesStored:  int64(stats.stored),
+		PagesSkipped: int64(stats.skipped),
+		Errors:       int64(stats.errors),
+		LastError:    lastErr,
+		ID:           job.id,
+	}); err != nil {
+		log.Printf("crawl %s: %v", job.id, err)
+	}
+	log.Printf("crawl %s %s: %d visited, %d stored", job.id, state, stats.visited, stats.stored)
+}
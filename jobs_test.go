@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/junwei890/rumbling/internal/database"
+)
+
+func testAPI(db *fakeDB, queueSize int) *apiConfig {
+	testFetcher := newHTTPFetcher(fetcherConfig{
+		userAgent:    userAgent,
+		totalTimeout: 10 * time.Second,
+	})
+	conn := sql.OpenDB(fakeConnector{db})
+	return &apiConfig{
+		db:   database.New(conn),
+		conn: conn,
+		robots: &robotsCache{
+			mu:      &sync.Mutex{},
+			entries: make(map[string]*robotsEntry),
+			fetcher: testFetcher,
+			agent:   crawlerName,
+		},
+		limiter: &hostLimiter{
+			mu:      &sync.Mutex{},
+			buckets: make(map[string]*tokenBucket),
+		},
+		queue:   make(chan crawlJob, queueSize),
+		cancels: make(map[string]context.CancelFunc),
+		jobsMu:  &sync.Mutex{},
+		limits: crawlLimits{
+			maxPages:       100,
+			maxDepth:       5,
+			maxConcurrency: 4,
+			maxDelay:       time.Second,
+			maxTimeout:     time.Minute,
+			defaultPages:   10,
+			frontierSize:   10000,
+		},
+		canon: defaultCanonicalizer,
+		redirects: redirectConfig{
+			limit: 10,
+			mode:  redirectRefuse,
+		},
+		body: bodyConfig{
+			maxBytes: 1 << 20,
+			mode:     bodyTruncate,
+		},
+		fetcher:    testFetcher,
+		directives: directivesConfig{agent: crawlerName},
+	}
+}
+
+func (f *fakeDB) states(id string) []string { // the states a crawl was moved through, in order
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	states := []string{}
+	for _, exec := range f.execs {
+		match := fakeQueryName.FindStringSubmatch(exec.query)
+		if match == nil || len(exec.args) == 0 || exec.args[len(exec.args)-1] != id {
+			continue
+		}
+		switch match[1] {
+		case "StartCrawl":
+			states = append(states, crawlRunning)
+		case "CancelQueuedCrawl":
+			states = append(states, crawlCancelled)
+		case "FinishCrawl":
+			states = append(states, exec.args[0].(string))
+		}
+	}
+	return states
+}
+
+func TestRunCrawlCancel(t *testing.T) {
+	blocked := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<p>home</p><a href="/slow">slow</a>`)
+		case "/slow":
+			blocked <- struct{}{}
+			select {
+			case <-release:
+			case <-req.Context().Done():
+			}
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	db := &fakeDB{mu: &sync.Mutex{}}
+	api := testAPI(db, 1)
+	job := crawlJob{
+		id:   "job",
+		url:  server.URL,
+		ctx:  api.registerCrawl("job"),
+		opts: crawlOptions{MaxPages: 10, MaxDepth: intPtr(1), Concurrency: 1, TimeoutSecs: 60},
+	}
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		api.runCrawl(job)
+	}()
+
+	<-blocked
+	if !api.cancelCrawl("job") {
+		t.Fatal("cancel failed, crawl not registered")
+	}
+	select {
+	case <-returned:
+	case <-time.After(2 * time.Second):
+		t.Fatal("cancel failed, runCrawl still running")
+	}
+
+	if result, expected := db.states("job"), []string{crawlRunning, crawlCancelled}; !reflect.DeepEqual(result, expected) {
+		t.Errorf("cancel failed, %v != %v", result, expected)
+	}
+	if result := len(db.inserted()); result != 1 { // the seed stored before cancelling is kept
+		t.Errorf("cancel failed, %v != %v", result, 1)
+	}
+	if _, ok := api.cancels["job"]; ok {
+		t.Errorf("cancel failed, crawl still registered")
+	}
+}
+
+func TestRunCrawl(t *testing.T) {
+	site := httptest.NewServer(siteHandler(func(path string) []string {
+		return nil
+	}))
+	defer site.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/robots.txt" { // a failing robots.txt would disallow the seed instead
+			http.NotFound(w, req)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+
+	testCases := []struct {
+		name     string
+		url      string
+		cancel   bool // cancelled while still queued
+		expected []string
+	}{
+		{
+			name:     "test case 1",
+			url:      site.URL,
+			expected: []string{crawlRunning, crawlDone},
+		},
+		{
+			name:     "test case 2",
+			url:      down.URL,
+			expected: []string{crawlRunning, crawlFailed},
+		},
+		{
+			name:     "test case 3",
+			url:      site.URL,
+			cancel:   true,
+			expected: []string{crawlCancelled},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			db := &fakeDB{mu: &sync.Mutex{}}
+			api := testAPI(db, 1)
+			api.retries = retryConfig{attempts: 0}
+			job := crawlJob{
+				id:   "job",
+				url:  testCase.url,
+				ctx:  api.registerCrawl("job"),
+				opts: crawlOptions{MaxPages: 5, MaxDepth: intPtr(1), Concurrency: 1, TimeoutSecs: 60},
+			}
+			if testCase.cancel {
+				api.cancelCrawl("job")
+			}
+			api.runCrawl(job)
+
+			if result := db.states("job"); !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+type linkRes struct {
+	Url        string `json:"url"`
+	AnchorText string `json:"anchor_text"`
+	Rel        string `json:"rel"`
+	CrawlID    string `json:"crawl_id"`
+}
+
+func (c *apiConfig) getOutboundLinks(w http.ResponseWriter, req *http.Request) {
+	type resData struct {
+		Url   string    `json:"url"`
+		Links []linkRes `json:"links"`
+	}
+
+	normUrl, ok := c.linkQueryUrl(w, req)
+	if !ok {
+		return
+	}
+	rows, err := c.db.ListOutboundLinks(req.Context(), normUrl)
+	if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := resData{
+		Url:   normUrl,
+		Links: []linkRes{},
+	}
+	for _, row := range rows {
+		res.Links = append(res.Links, linkRes{
+			Url:        row.TargetUrl,
+			AnchorText: row.AnchorText,
+			Rel:        row.Rel,
+			CrawlID:    row.CrawlID,
+		})
+	}
+	jsonResponseWriter(w, http.StatusOK, res)
+}
+
+func (c *apiConfig) getInboundLinks(w http.ResponseWriter, req *http.Request) {
+	type resData struct {
+		Url   string    `json:"url"`
+		Links []linkRes `json:"links"`
+	}
+
+	normUrl, ok := c.linkQueryUrl(w, req)
+	if !ok {
+		return
+	}
+	rows, err := c.db.ListInboundLinks(req.Context(), normUrl)
+	if err != nil {
+		errorResponseWriter(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	res := resData{
+		Url:   normUrl,
+		Links: []linkRes{},
+	}
+	for _, row := range rows {
+		res.Links = append(res.Links, linkRes{
+			Url:        row.SourceUrl,
+			AnchorText: row.AnchorText,
+			Rel:        row.Rel,
+			CrawlID:    row.CrawlID,
+		})
+	}
+	jsonResponseWriter(w, http.StatusOK, res)
+}
+
+func (c *apiConfig) linkQueryUrl(w http.ResponseWriter, req *http.Request) (string, bool) { // links are stored under canonical urls, so lookups are too
+	rawUrl := req.URL.Query().Get("url")
+	if rawUrl == "" {
+		errorResponseWriter(w, http.StatusBadRequest, errors.New("missing url"))
+		return "", false
+	}
+	normUrl, err := c.canon.canonicalize(rawUrl)
+	if err != nil {
+		errorResponseWriter(w, http.StatusBadRequest, err)
+		return "", false
+	}
+	return normUrl, true
+}
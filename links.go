@@ -0,0 +1,158 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const maxAnchorLength = 256 // runes of anchor text kept per link
+
+var followedRels = []string{"alternate", "canonical", "next", "prev"} // <link> rels that point at other pages, not assets
+
+type pageLink struct {
+	url    string
+	anchor string // visible text, or the alt text of images and areas
+	rel    string // lowercased rel attribute
+}
+
+func documentBase(pageUrl *url.URL, htmlTree *html.Node) *url.URL { // relative links resolve against the first <base href>, else the page itself
+	for n := range htmlTree.Descendants() {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Base {
+			continue
+		}
+		if href, ok := attrValue(n, "href"); ok {
+			if ref, err := url.Parse(strings.TrimSpace(href)); err == nil {
+				return pageUrl.ResolveReference(ref)
+			}
+		}
+		break // only the first base element counts
+	}
+	return pageUrl
+}
+
+func extractLinks(base *url.URL, htmlTree *html.Node) []pageLink {
+	links := []pageLink{}
+	add := func(href, anchor string, n *html.Node) {
+		if link, ok := resolveLink(base, href); ok {
+			rel, _ := attrValue(n, "rel")
+			links = append(links, pageLink{
+				url:    link,
+				anchor: anchor,
+				rel:    strings.Join(strings.Fields(strings.ToLower(rel)), " "),
+			})
+		}
+	}
+
+	for n := range htmlTree.Descendants() {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		switch n.DataAtom {
+		case atom.A:
+			if href, ok := attrValue(n, "href"); ok {
+				add(href, anchorText(n), n)
+			}
+		case atom.Area:
+			if href, ok := attrValue(n, "href"); ok {
+				alt, _ := attrValue(n, "alt")
+				add(href, cleanAnchor(alt), n)
+			}
+		case atom.Link:
+			rel, _ := attrValue(n, "rel")
+			if slices.ContainsFunc(strings.Fields(strings.ToLower(rel)), func(r string) bool {
+				return slices.Contains(followedRels, r)
+			}) {
+				if href, ok := attrValue(n, "href"); ok {
+					add(href, "", n)
+				}
+			}
+		case atom.Iframe, atom.Frame:
+			if src, ok := attrValue(n, "src"); ok {
+				add(src, "", n)
+			}
+		case atom.Meta:
+			if equiv, _ := attrValue(n, "http-equiv"); strings.EqualFold(equiv, "refresh") {
+				content, _ := attrValue(n, "content")
+				if target := metaRefreshTarget(content); target != "" {
+					add(target, "", n)
+				}
+			}
+		}
+	}
+	return links
+}
+
+func linkUrls(links []pageLink) []string {
+	urls := make([]string, 0, len(links))
+	for _, link := range links {
+		urls = append(urls, link.url)
+	}
+	return urls
+}
+
+func anchorText(n *html.Node) string { // falls back to image alt text for image links
+	text := []string{}
+	alt := ""
+	for child := range n.Descendants() {
+		if child.Type == html.TextNode {
+			text = append(text, child.Data)
+		} else if child.Type == html.ElementNode && child.DataAtom == atom.Img && alt == "" {
+			alt, _ = attrValue(child, "alt")
+		}
+	}
+	if anchor := cleanAnchor(strings.Join(text, " ")); anchor != "" {
+		return anchor
+	}
+	return cleanAnchor(alt)
+}
+
+func cleanAnchor(text string) string {
+	clean := strings.Join(strings.Fields(text), " ")
+	if runes := []rune(clean); len(runes) > maxAnchorLength {
+		clean = string(runes[:maxAnchorLength])
+	}
+	return clean
+}
+
+func resolveLink(base *url.URL, href string) (string, bool) { // drops mailto:, tel:, javascript:, data: and anything else that isn't http
+	ref, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "", false
+	}
+	resolved := base.ResolveReference(ref)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return "", false
+	}
+	resolved.Fragment = ""
+	return resolved.String(), true
+}
+
+func metaRefreshTarget(content string) string { // content looks like "5; url=/next"
+	_, rest, ok := strings.Cut(content, ";")
+	if !ok {
+		return ""
+	}
+	rest = strings.TrimSpace(rest)
+	if len(rest) < 4 || !strings.EqualFold(rest[:3], "url") {
+		return ""
+	}
+	rest = strings.TrimSpace(rest[3:])
+	target, ok := strings.CutPrefix(rest, "=")
+	if !ok {
+		return ""
+	}
+	return strings.Trim(strings.TrimSpace(target), `"'`)
+}
+
+func attrValue(n *html.Node, key string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestExtractLinks(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pageUrl  string
+		body     string
+		expected []string
+	}{
+		{
+			name:     "test case 1",
+			pageUrl:  "https://example.com/docs/v2/",
+			body:     `<a href="guide.html">guide</a><a href="../v1/">old</a><a href="/about">about</a>`,
+			expected: []string{"https://example.com/docs/v2/guide.html", "https://example.com/docs/v1/", "https://example.com/about"},
+		},
+		{
+			name:     "test case 2",
+			pageUrl:  "https://example.com/docs/v2/index.html",
+			body:     `<head><base href="/static/"></head><a href="guide.html">guide</a>`,
+			expected: []string{"https://example.com/static/guide.html"},
+		},
+		{
+			name:     "test case 3",
+			pageUrl:  "https://example.com/",
+			body:     `<a href="mailto:a@example.com">a</a><a href="tel:+123">b</a><a href="javascript:void(0)">c</a><a href="data:text/html,hi">d</a><a href="https://other.com/x#top">e</a>`,
+			expected: []string{"https://other.com/x"},
+		},
+		{
+			name:    "test case 4",
+			pageUrl: "https://example.com/a/",
+			body: `<head><link rel="stylesheet" href="/style.css"><link rel="next" href="page2"><meta http-equiv="Refresh" content="0; URL='/moved'"></head>` +
+				`<map><area href="region"></map><iframe src="/embed"></iframe>`,
+			expected: []string{"https://example.com/a/page2", "https://example.com/moved", "https://example.com/a/region", "https://example.com/embed"},
+		},
+		{
+			name:     "test case 5",
+			pageUrl:  "https://example.com/",
+			body:     `<a>no href</a><a href="http://[::1">broken</a>`,
+			expected: []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pageUrl, err := url.Parse(testCase.pageUrl)
+			if err != nil {
+				t.Fatalf("%s failed, unexpected error: %v", testCase.name, err)
+			}
+			htmlTree, err := html.Parse(strings.NewReader(testCase.body))
+			if err != nil {
+				t.Fatalf("%s failed, unexpected error: %v", testCase.name, err)
+			}
+			result := linkUrls(extractLinks(documentBase(pageUrl, htmlTree), htmlTree))
+			if comp := reflect.DeepEqual(result, testCase.expected); !comp {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestExtractLinkDetails(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		expected []pageLink
+	}{
+		{
+			name: "test case 1",
+			body: `<a href="/a" rel="NoFollow  Sponsored">  read
+				<b>more</b> </a>`,
+			expected: []pageLink{{url: "https://example.com/a", anchor: "read more", rel: "nofollow sponsored"}},
+		},
+		{
+			name:     "test case 2",
+			body:     `<a href="/b"><img src="/logo.png" alt="Home"></a><map><area href="/c" alt="Region"></map>`,
+			expected: []pageLink{{url: "https://example.com/b", anchor: "Home"}, {url: "https://example.com/c", anchor: "Region"}},
+		},
+		{
+			name:     "test case 3",
+			body:     `<head><link rel="next" href="/2"></head><iframe src="/embed"></iframe>`,
+			expected: []pageLink{{url: "https://example.com/2", rel: "next"}, {url: "https://example.com/embed"}},
+		},
+		{
+			name:     "test case 4",
+			body:     `<a href="/d">` + strings.Repeat("x", 300) + `</a>`,
+			expected: []pageLink{{url: "https://example.com/d", anchor: strings.Repeat("x", maxAnchorLength)}},
+		},
+	}
+
+	pageUrl := &url.URL{Scheme: "https", Host: "example.com", Path: "/"}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			htmlTree, err := html.Parse(strings.NewReader(testCase.body))
+			if err != nil {
+				t.Fatalf("%s failed, unexpected error: %v", testCase.name, err)
+			}
+			if result := extractLinks(pageUrl, htmlTree); !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestMetaRefreshTarget(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "test case 1",
+			content:  "5; url=/next",
+			expected: "/next",
+		},
+		{
+			name:     "test case 2",
+			content:  `0;URL="https://example.com/moved"`,
+			expected: "https://example.com/moved",
+		},
+		{
+			name:     "test case 3",
+			content:  "30",
+			expected: "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if result := metaRefreshTarget(testCase.content); result != testCase.expected {
+				t.Errorf("%s failed, %s != %s", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"database/sql"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -13,10 +17,34 @@ import (
 )
 
 type apiConfig struct {
-	db *database.Queries
+	db         *database.Queries
+	conn       *sql.DB
+	robots     *robotsCache
+	limiter    *hostLimiter
+	queue      chan crawlJob
+	cancels    map[string]context.CancelFunc // in-flight crawls by id
+	jobsMu     *sync.Mutex
+	limits     crawlLimits
+	dedup      dedupConfig
+	canon      canonicalizer
+	redirects  redirectConfig
+	retries    retryConfig
+	body       bodyConfig
+	fetcher    fetcher
+	warc       warcConfig
+	rank       rankConfig
+	directives directivesConfig
+	content    contentConfig
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "warc" { // offline tooling, no server or database needed
+		if err := runWarcCommand(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	config := apiConfig{}
 
 	err := godotenv.Load()
@@ -24,6 +52,76 @@ func main() {
 		log.Println("no environment variables loaded from .env file")
 	}
 
+	fetchConfig := fetcherConfig{
+		userAgent:      envString("FETCH_USER_AGENT", userAgent),
+		headers:        envHeaders("FETCH_HEADERS"),
+		connectTimeout: envDuration("FETCH_CONNECT_TIMEOUT", 10*time.Second),
+		readTimeout:    envDuration("FETCH_READ_TIMEOUT", 30*time.Second),
+		totalTimeout:   envDuration("FETCH_TIMEOUT", time.Minute),
+		tlsInsecure:    envBool("FETCH_TLS_INSECURE", false),
+		tlsMinVersion:  tls.VersionTLS12,
+		maxIdlePerHost: envInt("FETCH_MAX_IDLE_PER_HOST", 8),
+	}
+	if rawProxy := os.Getenv("FETCH_PROXY"); rawProxy != "" {
+		fetchConfig.proxy, err = url.Parse(rawProxy)
+		if err != nil {
+			log.Fatalf("invalid FETCH_PROXY: %v", err)
+		}
+	}
+	switch os.Getenv("FETCH_TLS_MIN_VERSION") {
+	case "":
+	case "1.2":
+		fetchConfig.tlsMinVersion = tls.VersionTLS12
+	case "1.3":
+		fetchConfig.tlsMinVersion = tls.VersionTLS13
+	default:
+		log.Fatal("FETCH_TLS_MIN_VERSION must be 1.2 or 1.3")
+	}
+	config.body = bodyConfig{
+		maxBytes: int64(envInt("CRAWL_MAX_BODY_BYTES", 10<<20)),
+		mode:     os.Getenv("BODY_LIMIT_POLICY"),
+	}
+	if config.body.mode == "" {
+		config.body.mode = bodyTruncate
+	} else if config.body.mode != bodyTruncate && config.body.mode != bodyReject {
+		log.Fatal("BODY_LIMIT_POLICY must be truncate or reject")
+	}
+	config.fetcher = newHTTPFetcher(fetchConfig)
+	archiveDir := envString("ARCHIVE_DIR", "archive")
+	switch envString("FETCH_MODE", fetchLive) {
+	case fetchLive:
+	case fetchRecord:
+		if err := os.MkdirAll(archiveDir, 0o755); err != nil {
+			log.Fatalf("archive directory not created: %v", err)
+		}
+		config.fetcher = &recordingFetcher{
+			next:     config.fetcher,
+			dir:      archiveDir,
+			maxBytes: config.body.maxBytes,
+		}
+	case fetchReplay:
+		config.fetcher = &replayFetcher{dir: archiveDir}
+	default:
+		log.Fatal("FETCH_MODE must be live, record or replay")
+	}
+	config.robots = &robotsCache{
+		mu:      &sync.Mutex{},
+		entries: make(map[string]*robotsEntry),
+		fetcher: config.fetcher,
+		agent:   productToken(fetchConfig.userAgent),
+	}
+
+	config.limiter = &hostLimiter{ // shared so concurrent crawls of one host are paced together
+		mu:           &sync.Mutex{},
+		rate:         envFloat("CRAWL_HOST_RPS", 1),
+		burst:        envInt("CRAWL_HOST_BURST", 2),
+		minDelay:     envDuration("CRAWL_HOST_MIN_DELAY", 500*time.Millisecond),
+		buckets:      make(map[string]*tokenBucket),
+		failureLimit: envInt("CRAWL_HOST_FAILURES", 5),
+		backoff:      envDuration("CRAWL_HOST_BACKOFF", 30*time.Second),
+		maxBackoff:   envDuration("CRAWL_HOST_MAX_BACKOFF", 10*time.Minute),
+	}
+
 	dbUrl := os.Getenv("DB_URL")
 	if dbUrl == "" {
 		log.Fatal("no database url provided")
@@ -36,8 +134,22 @@ func main() {
 
 	dbQueries := database.New(db)
 	config.db = dbQueries
+	config.conn = db
 	log.Println("connected to database")
 
+	config.canon = canonicalizer{
+		stripParams:    envList("CANONICAL_STRIP_PARAMS", defaultCanonicalizer.stripParams),
+		keepParams:     envList("CANONICAL_KEEP_PARAMS", nil),
+		wwwEquivalent:  envBool("CANONICAL_WWW_EQUIVALENT", false),
+		honorCanonical: envBool("CANONICAL_HONOR_REL", true),
+	}
+	if len(os.Args) > 1 && os.Args[1] == "canonicalize" { // one off, after upgrading from plain normalized urls
+		if err := runCanonicalizeCommand(context.Background(), os.Args[2:], config.db, config.canon, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		log.Fatal("no port provided")
@@ -45,7 +157,85 @@ func main() {
 
 	plexer := http.NewServeMux()
 
+	config.limits = crawlLimits{
+		maxPages:       envInt("CRAWL_MAX_PAGES", 1000),
+		maxDepth:       envInt("CRAWL_MAX_DEPTH", 10),
+		maxConcurrency: envInt("CRAWL_MAX_CONCURRENCY", 20),
+		maxDelay:       envDuration("CRAWL_MAX_DELAY", time.Minute),
+		maxTimeout:     envDuration("CRAWL_MAX_TIMEOUT", time.Hour),
+		defaultPages:   envInt("CRAWL_DEFAULT_PAGES", 20),
+		frontierSize:   envInt("CRAWL_FRONTIER_SIZE", 10000),
+	}
+	config.dedup = dedupConfig{
+		mode:     os.Getenv("DEDUP_MODE"),
+		distance: envInt("DEDUP_DISTANCE", 3),
+	}
+	if config.dedup.mode == "" {
+		config.dedup.mode = dedupLink
+	} else if config.dedup.mode != dedupLink && config.dedup.mode != dedupSkip {
+		log.Fatal("DEDUP_MODE must be link or skip")
+	}
+	config.directives.agent = productToken(fetchConfig.userAgent)
+	for _, entry := range envList("ROBOTS_META_IGNORE_HOSTS", nil) { // operator override for internal sites
+		host, err := scopeHost(entry, config.canon)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.directives.ignoreHosts = append(config.directives.ignoreHosts, host)
+	}
+	config.content = contentConfig{
+		mode:     os.Getenv("CONTENT_EXTRACTION"),
+		keepFull: envBool("CONTENT_KEEP_FULL", false),
+	}
+	if config.content.mode == "" {
+		config.content.mode = contentMain
+	} else if config.content.mode != contentMain && config.content.mode != contentFull {
+		log.Fatal("CONTENT_EXTRACTION must be main or full")
+	}
+	config.redirects = redirectConfig{
+		limit: envInt("CRAWL_MAX_REDIRECTS", 10),
+		mode:  os.Getenv("REDIRECT_POLICY"),
+	}
+	if config.redirects.mode == "" {
+		config.redirects.mode = redirectRefuse
+	} else if config.redirects.mode != redirectRefuse && config.redirects.mode != redirectRecord {
+		log.Fatal("REDIRECT_POLICY must be refuse or record")
+	}
+	config.retries = retryConfig{
+		attempts: envInt("CRAWL_RETRIES", 3),
+		base:     envDuration("CRAWL_RETRY_BASE", 500*time.Millisecond),
+		max:      envDuration("CRAWL_RETRY_MAX", 30*time.Second),
+	}
+	config.warc = warcConfig{
+		dir:      os.Getenv("WARC_DIR"),
+		maxBytes: int64(envInt("WARC_MAX_BYTES", 1<<30)),
+	}
+	config.rank = rankConfig{
+		damping:    envFloat("PAGERANK_DAMPING", 0.85),
+		iterations: envInt("PAGERANK_ITERATIONS", 50),
+	}
+	if config.rank.damping <= 0 || config.rank.damping >= 1 {
+		log.Fatal("PAGERANK_DAMPING must be between 0 and 1")
+	}
+	config.queue = make(chan crawlJob, envInt("CRAWL_QUEUE_SIZE", 100))
+	config.cancels = make(map[string]context.CancelFunc)
+	config.jobsMu = &sync.Mutex{}
+	for range envInt("CRAWL_WORKERS", 2) {
+		go config.crawlWorker()
+	}
+
 	plexer.HandleFunc("POST /api/data", config.postData)
+	plexer.HandleFunc("GET /api/crawls/{id}", config.getCrawl)
+	plexer.HandleFunc("DELETE /api/crawls/{id}", config.deleteCrawl)
+	plexer.HandleFunc("GET /api/crawls/{id}/duplicates", config.getCrawlDuplicates)
+	plexer.HandleFunc("GET /api/crawls/{id}/redirects", config.getCrawlRedirects)
+	plexer.HandleFunc("GET /api/crawls/{id}/broken-links", config.getCrawlBrokenLinks)
+	plexer.HandleFunc("GET /api/crawls/{id}/scope", config.getCrawlScope)
+	plexer.HandleFunc("GET /api/crawls/{id}/skipped", config.getCrawlSkipped)
+	plexer.HandleFunc("GET /api/pages", config.getPages)
+	plexer.HandleFunc("GET /api/page", config.getPage)
+	plexer.HandleFunc("GET /api/links/outbound", config.getOutboundLinks)
+	plexer.HandleFunc("GET /api/links/inbound", config.getInboundLinks)
 
 	server := &http.Server{
 		Addr:              port,
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type crawlLimits struct { // server side ceilings, set by the operator
+	maxPages       int
+	maxDepth       int
+	maxConcurrency int
+	maxDelay       time.Duration
+	maxTimeout     time.Duration
+	defaultPages   int
+	frontierSize   int // most urls waiting to be crawled at once
+}
+
+type crawlOptions struct {
+	MaxPages     int      `json:"max_pages"`
+	MaxDepth     *int     `json:"max_depth"` // nil takes the server maximum, 0 crawls only the seed
+	Concurrency  int      `json:"concurrency"`
+	DelayMs      int      `json:"delay_ms"`        // minimum gap between requests to a host for this crawl
+	Include      []string `json:"include"`         // robots.txt style path patterns, a path must match one of them
+	Exclude      []string `json:"exclude"`         // robots.txt style path patterns, a matching path is skipped
+	TimeoutSecs  int      `json:"timeout_seconds"` // wall clock limit for the whole crawl
+	AllowedHosts []string `json:"allowed_hosts"`   // hosts crawled besides the seed's, *.example.com allows every subdomain
+	PathPrefixes []string `json:"path_prefixes"`   // a path must start with one of them
+	IncludeRegex []string `json:"include_regex"`   // matched against the canonical url, one of them must match
+	ExcludeRegex []string `json:"exclude_regex"`   // matched against the canonical url, a match is skipped
+}
+
+func (o *crawlOptions) validate(limits crawlLimits) error { // fills in defaults for anything left at zero
+	if o.MaxPages < 0 || o.depth() < 0 || o.Concurrency < 0 || o.DelayMs < 0 || o.TimeoutSecs < 0 {
+		return errors.New("crawl options cannot be negative")
+	}
+
+	if o.MaxPages == 0 {
+		o.MaxPages = min(limits.defaultPages, limits.maxPages)
+	} else if o.MaxPages > limits.maxPages {
+		return fmt.Errorf("max_pages cannot exceed %d", limits.maxPages)
+	}
+	if o.MaxDepth == nil {
+		maxDepth := limits.maxDepth
+		o.MaxDepth = &maxDepth
+	} else if *o.MaxDepth > limits.maxDepth {
+		return fmt.Errorf("max_depth cannot exceed %d", limits.maxDepth)
+	}
+	if o.Concurrency == 0 {
+		o.Concurrency = min(5, limits.maxConcurrency)
+	} else if o.Concurrency > limits.maxConcurrency {
+		return fmt.Errorf("concurrency cannot exceed %d", limits.maxConcurrency)
+	}
+	if o.delay() > limits.maxDelay {
+		return fmt.Errorf("delay_ms cannot exceed %d", limits.maxDelay.Milliseconds())
+	}
+	if o.TimeoutSecs == 0 {
+		o.TimeoutSecs = int(limits.maxTimeout.Seconds())
+	} else if o.timeout() > limits.maxTimeout {
+		return fmt.Errorf("timeout_seconds cannot exceed %d", int(limits.maxTimeout.Seconds()))
+	}
+
+	for _, pattern := range append(o.Include, o.Exclude...) {
+		if pattern == "" || pattern[0] != '/' && pattern[0] != '*' {
+			return fmt.Errorf("path pattern %q must start with / or *", pattern)
+		}
+	}
+	for _, prefix := range o.PathPrefixes {
+		if !strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("path prefix %q must start with /", prefix)
+		}
+	}
+	for _, pattern := range append(o.IncludeRegex, o.ExcludeRegex...) {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid regex %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+func (o crawlOptions) depth() int { // only zero before validate has filled in the default
+	if o.MaxDepth == nil {
+		return 0
+	}
+	return *o.MaxDepth
+}
+
+func (o crawlOptions) delay() time.Duration {
+	return time.Duration(o.DelayMs) * time.Millisecond
+}
+
+func (o crawlOptions) timeout() time.Duration {
+	return time.Duration(o.TimeoutSecs) * time.Second
+}
+
+func (o crawlOptions) pathAllowed(urlStruct *url.URL) (bool, string) { // returns the reason when a path is filtered out
+	path := urlStruct.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	for _, pattern := range o.Exclude {
+		if robotsMatch(pattern, path) {
+			return false, "excluded by pattern " + pattern
+		}
+	}
+	if len(o.Include) == 0 {
+		return true, ""
+	}
+	for _, pattern := range o.Include {
+		if robotsMatch(pattern, path) {
+			return true, ""
+		}
+	}
+	return false, "not matched by any include pattern"
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestCrawlOptionsValidate(t *testing.T) {
+	limits := crawlLimits{
+		maxPages:       100,
+		maxDepth:       5,
+		maxConcurrency: 10,
+		maxDelay:       10 * time.Second,
+		maxTimeout:     time.Hour,
+		defaultPages:   20,
+	}
+
+	testCases := []struct {
+		name         string
+		input        crawlOptions
+		expected     crawlOptions
+		errorPresent bool
+	}{
+		{
+			name:  "test case 1",
+			input: crawlOptions{},
+			expected: crawlOptions{
+				MaxPages:    20,
+				MaxDepth:    intPtr(5),
+				Concurrency: 5,
+				TimeoutSecs: 3600,
+			},
+			errorPresent: false,
+		},
+		{
+			name: "test case 2",
+			input: crawlOptions{
+				MaxPages:    50,
+				MaxDepth:    intPtr(2),
+				Concurrency: 1,
+				DelayMs:     2000,
+				Include:     []string{"/docs/"},
+				TimeoutSecs: 60,
+			},
+			expected: crawlOptions{
+				MaxPages:    50,
+				MaxDepth:    intPtr(2),
+				Concurrency: 1,
+				DelayMs:     2000,
+				Include:     []string{"/docs/"},
+				TimeoutSecs: 60,
+			},
+			errorPresent: false,
+		},
+		{
+			name:         "test case 3",
+			input:        crawlOptions{MaxPages: 101},
+			errorPresent: true,
+		},
+		{
+			name:         "test case 4",
+			input:        crawlOptions{Concurrency: -1},
+			errorPresent: true,
+		},
+		{
+			name:         "test case 5",
+			input:        crawlOptions{DelayMs: 10001},
+			errorPresent: true,
+		},
+		{
+			name:         "test case 6",
+			input:        crawlOptions{Exclude: []string{"docs"}},
+			errorPresent: true,
+		},
+		{
+			name:         "test case 7",
+			input:        crawlOptions{PathPrefixes: []string{"docs/"}},
+			errorPresent: true,
+		},
+		{
+			name:         "test case 8",
+			input:        crawlOptions{ExcludeRegex: []string{"(unclosed"}},
+			errorPresent: true,
+		},
+		{
+			name:  "test case 9",
+			input: crawlOptions{MaxDepth: intPtr(0)},
+			expected: crawlOptions{
+				MaxPages:    20,
+				MaxDepth:    intPtr(0),
+				Concurrency: 5,
+				TimeoutSecs: 3600,
+			},
+			errorPresent: false,
+		},
+		{
+			name:         "test case 10",
+			input:        crawlOptions{MaxDepth: intPtr(6)},
+			errorPresent: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := testCase.input.validate(limits)
+			if (err != nil) != testCase.errorPresent {
+				t.Errorf("%s failed, expecting err = %v", testCase.name, err)
+			} else if err == nil && !reflect.DeepEqual(testCase.input, testCase.expected) {
+				t.Errorf("%s failed, %v != %v", testCase.name, testCase.input, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestPathAllowed(t *testing.T) {
+	opts := crawlOptions{
+		Include: []string{"/docs/", "/blog/*.html$"},
+		Exclude: []string{"/docs/v1/"},
+	}
+
+	testCases := []struct {
+		name     string
+		url      string
+		expected bool
+	}{
+		{
+			name:     "test case 1",
+			url:      "https://example.com/docs/v2/intro",
+			expected: true,
+		},
+		{
+			name:     "test case 2",
+			url:      "https://example.com/docs/v1/intro",
+			expected: false,
+		},
+		{
+			name:     "test case 3",
+			url:      "https://example.com/blog/2024/post.html",
+			expected: true,
+		},
+		{
+			name:     "test case 4",
+			url:      "https://example.com/about",
+			expected: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			urlStruct, err := url.Parse(testCase.url)
+			if err != nil {
+				t.Fatalf("%s failed, unexpected error: %v", testCase.name, err)
+			}
+			if result, _ := opts.pathAllowed(urlStruct); result != testCase.expected {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
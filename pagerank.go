@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/junwei890/rumbling/internal/database"
+)
+
+const rankTolerance = 1e-9 // total change in an iteration below which the scores have converged
+
+type rankConfig struct {
+	damping    float64 // chance of following a link rather than jumping to a random page
+	iterations int     // 0 disables ranking
+}
+
+func pageRank(graph map[string][]string, damping float64, iterations int) map[string]float64 { // scores sum to 1, links to pages outside the graph are ignored
+	nodes := make([]string, 0, len(graph))
+	for node := range graph {
+		nodes = append(nodes, node)
+	}
+	slices.Sort(nodes) // fixed order so float sums come out the same every run
+	index := make(map[string]int, len(nodes))
+	for i, node := range nodes {
+		index[node] = i
+	}
+
+	out := make([][]int, len(nodes))
+	for i, node := range nodes {
+		seen := make(map[int]struct{})
+		for _, target := range graph[node] {
+			j, ok := index[target]
+			if !ok || j == i { // self links don't say anything about importance
+				continue
+			}
+			if _, ok := seen[j]; ok {
+				continue
+			}
+			seen[j] = struct{}{}
+			out[i] = append(out[i], j)
+		}
+	}
+
+	n := float64(len(nodes))
+	rank := make([]float64, len(nodes))
+	for i := range rank {
+		rank[i] = 1 / n
+	}
+	for range iterations {
+		next := make([]float64, len(nodes))
+		dangling := 0.0 // pages without links spread their rank over every page
+		for i, targets := range out {
+			if len(targets) == 0 {
+				dangling += rank[i]
+				continue
+			}
+			share := rank[i] / float64(len(targets))
+			for _, j := range targets {
+				next[j] += share
+			}
+		}
+
+		base := (1-damping)/n + damping*dangling/n
+		delta := 0.0
+		for i := range next {
+			next[i] = base + damping*next[i]
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank = next
+		if delta < rankTolerance {
+			break
+		}
+	}
+
+	scores := make(map[string]float64, len(nodes))
+	for i, node := range nodes {
+		scores[node] = rank[i]
+	}
+	return scores
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`) // for LIKE ... ESCAPE '\'
+
+func domainPatterns(root string) (string, string, string) { // the root itself, its paths and its query strings, but not hosts that merely share a prefix
+	prefix := likeEscaper.Replace(root) // an _ in a host would match any character
+	return root, prefix + "/%", prefix + "?%"
+}
+
+func (c *crawlerConfig) rankPages(ctx context.Context) error { // ranks every stored page of the hosts the crawl stored on, earlier crawls included, since they share one graph
+	c.mu.Lock()
+	roots := make([]string, 0, len(c.roots))
+	for root := range c.roots {
+		roots = append(roots, root)
+	}
+	c.mu.Unlock()
+	slices.Sort(roots)
+
+	graph := make(map[string][]string)
+	links := []database.ListDomainLinksRow{}
+	for _, root := range roots {
+		root, pathPrefix, queryPrefix := domainPatterns(root)
+		pages, err := c.db.ListDomainPages(ctx, database.ListDomainPagesParams{
+			Root:        root,
+			PathPrefix:  pathPrefix,
+			QueryPrefix: queryPrefix,
+		})
+		if err != nil {
+			return err
+		}
+		for _, page := range pages {
+			graph[page] = nil
+		}
+		rootLinks, err := c.db.ListDomainLinks(ctx, database.ListDomainLinksParams{
+			Root:        root,
+			PathPrefix:  pathPrefix,
+			QueryPrefix: queryPrefix,
+		})
+		if err != nil {
+			return err
+		}
+		links = append(links, rootLinks...)
+	}
+	for _, link := range links { // links between the hosts count too
+		if _, ok := graph[link.SourceUrl]; ok {
+			graph[link.SourceUrl] = append(graph[link.SourceUrl], link.TargetUrl)
+		}
+	}
+
+	for page, score := range pageRank(graph, c.rank.damping, c.rank.iterations) {
+		if err := c.db.UpdatePageRank(ctx, database.UpdatePageRankParams{
+			Pagerank: sql.NullFloat64{
+				Float64: score,
+				Valid:   true,
+			},
+			Url: page,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"math"
+	"reflect"
+	"regexp"
+	"sync"
+	"testing"
+)
+
+func TestPageRank(t *testing.T) {
+	testCases := []struct {
+		name     string
+		graph    map[string][]string
+		expected map[string]float64
+	}{
+		{
+			name:     "test case 1",
+			graph:    map[string][]string{"a": {"b"}, "b": {"a"}},
+			expected: map[string]float64{"a": 0.5, "b": 0.5},
+		},
+		{
+			name:     "test case 2",
+			graph:    map[string][]string{"a": {"b", "c"}, "b": {"c"}, "c": {"a"}},
+			expected: map[string]float64{"a": 0.3878, "b": 0.2148, "c": 0.3974},
+		},
+		{
+			name:     "test case 3",
+			graph:    map[string][]string{"a": {"b"}, "b": nil},
+			expected: map[string]float64{"a": 0.3509, "b": 0.6491},
+		},
+		{
+			name:     "test case 4",
+			graph:    map[string][]string{"a": {"a", "https://other.com", "b", "b"}, "b": nil},
+			expected: map[string]float64{"a": 0.3509, "b": 0.6491},
+		},
+		{
+			name:     "test case 5",
+			graph:    map[string][]string{},
+			expected: map[string]float64{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := pageRank(testCase.graph, 0.85, 100)
+			if len(result) != len(testCase.expected) {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+				return
+			}
+			total := 0.0
+			for node, score := range result {
+				total += score
+				if math.Abs(score-testCase.expected[node]) > 1e-4 {
+					t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+					return
+				}
+			}
+			if len(result) != 0 && math.Abs(total-1) > 1e-9 {
+				t.Errorf("%s failed, scores sum to %v", testCase.name, total)
+			}
+		})
+	}
+}
+
+func TestRankPages(t *testing.T) {
+	pages := map[string][]string{ // root to its stored pages
+		"https://a.com": {"https://a.com", "https://a.com/x"},
+		"https://b.com": {"https://b.com"},
+	}
+	graph := map[string][]string{
+		"https://a.com":   {"https://b.com"},
+		"https://a.com/x": {"https://a.com"},
+		"https://b.com":   {"https://a.com/x"},
+	}
+	db := &fakeDB{
+		mu: &sync.Mutex{},
+		answer: func(name string, args []driver.Value) [][]driver.Value {
+			rows := [][]driver.Value{}
+			for _, page := range pages[args[0].(string)] {
+				switch name {
+				case "ListDomainPages":
+					rows = append(rows, []driver.Value{page})
+				case "ListDomainLinks":
+					for _, target := range graph[page] {
+						rows = append(rows, []driver.Value{page, target})
+					}
+				}
+			}
+			return rows
+		},
+	}
+	crawler := testCrawler(t, db, "https://a.com", crawlOptions{})
+	crawler.rank = rankConfig{damping: 0.85, iterations: 50}
+	for root := range pages { // both hosts had pages stored by the crawl
+		crawler.roots[root] = make(chan struct{})
+	}
+	if err := crawler.rankPages(context.Background()); err != nil {
+		t.Fatalf("rank failed, unexpected error: %v", err)
+	}
+
+	result := make(map[string]float64)
+	for _, row := range db.written("UPDATE", "data") {
+		result[row["url"].(string)] = row["pagerank"].(float64)
+	}
+	if expected := pageRank(graph, 0.85, 50); !reflect.DeepEqual(result, expected) {
+		t.Errorf("rank failed, %v != %v", result, expected)
+	}
+}
+
+func likeMatch(pattern, s string) bool { // sqlite's LIKE ... ESCAPE '\', minus its case folding
+	expr := "^"
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+			expr += regexp.QuoteMeta(pattern[i : i+1])
+		case '%':
+			expr += ".*"
+		case '_':
+			expr += "."
+		default:
+			expr += regexp.QuoteMeta(pattern[i : i+1])
+		}
+	}
+	return regexp.MustCompile(expr + "$").MatchString(s)
+}
+
+func TestDomainPatterns(t *testing.T) {
+	testCases := []struct {
+		name     string
+		root     string
+		url      string
+		expected bool
+	}{
+		{
+			name:     "test case 1",
+			root:     "http://my_site.com",
+			url:      "http://my_site.com/about",
+			expected: true,
+		},
+		{
+			name:     "test case 2",
+			root:     "http://my_site.com",
+			url:      "http://myXsite.com/about",
+			expected: false,
+		},
+		{
+			name:     "test case 3",
+			root:     "http://my_site.com",
+			url:      "http://my_site.com?page=2",
+			expected: true,
+		},
+		{
+			name:     "test case 4",
+			root:     "http://my_site.com",
+			url:      "http://my_site.company/about",
+			expected: false,
+		},
+		{
+			name:     "test case 5",
+			root:     "http://100%.com",
+			url:      "http://100percent.com/about",
+			expected: false,
+		},
+		{
+			name:     "test case 6",
+			root:     `http://a\b.com`,
+			url:      `http://a\b.com/about`,
+			expected: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			root, pathPrefix, queryPrefix := domainPatterns(testCase.root)
+			result := testCase.url == root || likeMatch(pathPrefix, testCase.url) || likeMatch(queryPrefix, testCase.url)
+			if result != testCase.expected {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
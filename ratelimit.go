@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type tokenBucket struct {
+	tokens   float64
+	last     time.Time // last refill
+	prev     time.Time // last time a request was let through
+	failures int       // consecutive transient failures
+	until    time.Time // no requests before this, set while the host is backed off
+}
+
+type hostLimiter struct {
+	mu           *sync.Mutex
+	rate         float64 // requests per second per host, 0 disables the bucket
+	burst        int
+	minDelay     time.Duration
+	buckets      map[string]*tokenBucket
+	failureLimit int           // consecutive failures before the whole host is backed off, 0 disables
+	backoff      time.Duration // first host backoff, doubled on every further failure
+	maxBackoff   time.Duration
+}
+
+func (h *hostLimiter) bucket(host string, now time.Time) *tokenBucket { // callers hold h.mu
+	bucket, ok := h.buckets[host]
+	if !ok {
+		bucket = &tokenBucket{
+			tokens: float64(h.burst),
+			last:   now,
+		}
+		h.buckets[host] = bucket
+	}
+	return bucket
+}
+
+func (h *hostLimiter) reserve(host string, crawlDelay time.Duration, now time.Time) time.Time { // returns when the caller may send its request
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	bucket := h.bucket(host, now)
+
+	at := now
+	if h.rate > 0 {
+		bucket.tokens = min(float64(h.burst), bucket.tokens+now.Sub(bucket.last).Seconds()*h.rate)
+		bucket.last = now
+		bucket.tokens-- // may go negative, which queues the caller behind earlier reservations
+		if bucket.tokens < 0 {
+			at = now.Add(time.Duration(-bucket.tokens / h.rate * float64(time.Second)))
+		}
+	}
+
+	if at.Before(bucket.until) {
+		at = bucket.until
+	}
+
+	gap := max(h.minDelay, crawlDelay) // robots.txt crawl-delay can only slow us down
+	if !bucket.prev.IsZero() {
+		if earliest := bucket.prev.Add(gap); at.Before(earliest) {
+			at = earliest
+		}
+	}
+	bucket.prev = at
+	return at
+}
+
+func (h *hostLimiter) wait(ctx context.Context, host string, crawlDelay time.Duration) error {
+	at := h.reserve(host, crawlDelay, time.Now())
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (h *hostLimiter) failed(host string, retryAfter time.Duration, now time.Time) { // a host that keeps failing is given time to recover
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	bucket := h.bucket(host, now)
+	bucket.failures++
+
+	pause := retryAfter // the server asked every client to wait, not just this request
+	if h.failureLimit > 0 && bucket.failures >= h.failureLimit {
+		backoff := h.maxBackoff
+		if shift := bucket.failures - h.failureLimit; shift < 32 && h.backoff<<shift < h.maxBackoff {
+			backoff = h.backoff << shift
+		}
+		pause = max(pause, backoff)
+	}
+	if until := now.Add(pause); until.After(bucket.until) {
+		bucket.until = until
+	}
+}
+
+func (h *hostLimiter) succeeded(host string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if bucket, ok := h.buckets[host]; ok {
+		bucket.failures = 0
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHostLimiterReserve(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		limiter    *hostLimiter
+		hosts      []string
+		crawlDelay time.Duration
+		expected   []time.Duration // offsets from start
+	}{
+		{
+			name: "test case 1",
+			limiter: &hostLimiter{
+				rate:  2,
+				burst: 2,
+			},
+			hosts:    []string{"a.com", "a.com", "a.com", "a.com"},
+			expected: []time.Duration{0, 0, 500 * time.Millisecond, time.Second},
+		},
+		{
+			name: "test case 2",
+			limiter: &hostLimiter{
+				rate:  2,
+				burst: 1,
+			},
+			hosts:    []string{"a.com", "b.com", "a.com", "b.com"},
+			expected: []time.Duration{0, 0, 500 * time.Millisecond, 500 * time.Millisecond},
+		},
+		{
+			name: "test case 3",
+			limiter: &hostLimiter{
+				rate:     10,
+				burst:    5,
+				minDelay: 300 * time.Millisecond,
+			},
+			hosts:    []string{"a.com", "a.com", "a.com"},
+			expected: []time.Duration{0, 300 * time.Millisecond, 600 * time.Millisecond},
+		},
+		{
+			name: "test case 4",
+			limiter: &hostLimiter{
+				rate:     0,
+				minDelay: 100 * time.Millisecond,
+			},
+			hosts:      []string{"a.com", "a.com"},
+			crawlDelay: 2 * time.Second,
+			expected:   []time.Duration{0, 2 * time.Second},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.limiter.mu = &sync.Mutex{}
+			testCase.limiter.buckets = make(map[string]*tokenBucket)
+
+			result := []time.Duration{}
+			for _, host := range testCase.hosts {
+				result = append(result, testCase.limiter.reserve(host, testCase.crawlDelay, start).Sub(start))
+			}
+			if comp := reflect.DeepEqual(result, testCase.expected); !comp {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestHostLimiterFailures(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		failures   int
+		retryAfter time.Duration
+		succeeded  bool
+		expected   time.Duration // offset of the next reservation from start
+	}{
+		{
+			name:     "test case 1",
+			failures: 1,
+			expected: 0,
+		},
+		{
+			name:     "test case 2",
+			failures: 3,
+			expected: time.Second,
+		},
+		{
+			name:     "test case 3",
+			failures: 5,
+			expected: 4 * time.Second,
+		},
+		{
+			name:     "test case 4",
+			failures: 10,
+			expected: 5 * time.Second,
+		},
+		{
+			name:       "test case 5",
+			failures:   1,
+			retryAfter: 2 * time.Second,
+			expected:   2 * time.Second,
+		},
+		{
+			name:      "test case 6",
+			failures:  2,
+			succeeded: true,
+			expected:  0,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			limiter := &hostLimiter{
+				mu:           &sync.Mutex{},
+				buckets:      make(map[string]*tokenBucket),
+				failureLimit: 3,
+				backoff:      time.Second,
+				maxBackoff:   5 * time.Second,
+			}
+			for range testCase.failures {
+				limiter.failed("a.com", testCase.retryAfter, start)
+			}
+			if testCase.succeeded {
+				limiter.succeeded("a.com")
+				limiter.failed("a.com", 0, start)
+			}
+			if result := limiter.reserve("a.com", 0, start).Sub(start); result != testCase.expected {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	contentMain = "main" // store what the main content detector keeps
+	contentFull = "full" // store all of the page's text
+
+	minScoredLength = 25  // shorter blocks are usually labels and buttons, too small to say where the article is
+	maxLinkDensity  = 0.5 // blocks that are mostly link text are menus and link lists
+	classWeight     = 25
+)
+
+var (
+	unlikelyClass = regexp.MustCompile(`banner|breadcrumb|comment|consent|cookie|disqus|footer|header|menu|modal|nav|popup|promo|related|share|sidebar|skip|social|sponsor|subscribe|widget`)
+	likelyClass   = regexp.MustCompile(`article|body|content|entry|hentry|main|post|story|text`)
+)
+
+var boilerplateElements = map[atom.Atom]struct{}{ // semantic tags for everything around the content
+	atom.Nav:    {},
+	atom.Footer: {},
+	atom.Aside:  {},
+	atom.Form:   {},
+}
+
+type contentConfig struct {
+	mode     string
+	keepFull bool // also store the full text next to the main content, for comparing the two
+}
+
+type textBlock struct {
+	text  string
+	links int        // characters inside links
+	node  *html.Node // the innermost block element holding the text
+}
+
+func (b textBlock) linkDensity() float64 {
+	return float64(b.links) / float64(len(b.text))
+}
+
+func mainBlocks(blocks []textBlock) []string { // readability style, scores the parents of text blocks and keeps the best one
+	kept := []textBlock{}
+	for _, block := range blocks {
+		if !boilerplate(block.node) && block.linkDensity() <= maxLinkDensity {
+			kept = append(kept, block)
+		}
+	}
+
+	scores := map[*html.Node]float64{} // containers of paragraphs are candidates, not the paragraphs
+	order := []*html.Node{}            // first scored first, so ties go to the earlier container
+	add := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			order = append(order, n)
+			scores[n] = nodeWeight(n)
+		}
+		scores[n] += score
+	}
+	for _, block := range kept {
+		if len(block.text) < minScoredLength {
+			continue
+		}
+		score := 1 + float64(strings.Count(block.text, ",")) + min(float64(len(block.text))/100, 3) // long, comma heavy prose looks like writing
+		add(block.node.Parent, score)
+		if block.node.Parent != nil {
+			add(block.node.Parent.Parent, score/2)
+		}
+	}
+
+	var top *html.Node
+	best := 0.0
+	for _, n := range order {
+		score := scores[n] * (1 - containerLinkDensity(n, blocks))
+		if top == nil || score > best {
+			top, best = n, score
+		}
+	}
+
+	res := []string{}
+	for _, block := range kept {
+		if top == nil || within(block.node, top) {
+			res = append(res, block.text)
+		}
+	}
+	return res
+}
+
+func nodeWeight(n *html.Node) float64 {
+	weight := 0.0
+	if n.DataAtom == atom.Main || n.DataAtom == atom.Article {
+		weight += classWeight
+	}
+	names := strings.ToLower(classAndID(n))
+	if likelyClass.MatchString(names) {
+		weight += classWeight
+	}
+	if unlikelyClass.MatchString(names) {
+		weight -= classWeight
+	}
+	return weight
+}
+
+func boilerplate(n *html.Node) bool { // whether anything around the node marks it as navigation, chrome or a banner
+	for curr := n; curr != nil; curr = curr.Parent {
+		if curr.Type != html.ElementNode {
+			continue
+		}
+		if _, ok := boilerplateElements[curr.DataAtom]; ok {
+			return true
+		}
+		switch curr.DataAtom {
+		case atom.Html, atom.Body, atom.Main, atom.Article: // page wide classes say little about the content
+			continue
+		case atom.Header:
+			if !insideContent(curr) { // site headers, not article headers
+				return true
+			}
+		}
+		if names := strings.ToLower(classAndID(curr)); unlikelyClass.MatchString(names) && !likelyClass.MatchString(names) {
+			return true
+		}
+		if role, _ := attrValue(curr, "role"); role == "navigation" || role == "banner" || role == "contentinfo" || role == "dialog" {
+			return true
+		}
+	}
+	return false
+}
+
+func insideContent(n *html.Node) bool {
+	for curr := n.Parent; curr != nil; curr = curr.Parent {
+		if curr.DataAtom == atom.Article || curr.DataAtom == atom.Main {
+			return true
+		}
+	}
+	return false
+}
+
+func containerLinkDensity(n *html.Node, blocks []textBlock) float64 {
+	text, links := 0, 0
+	for _, block := range blocks {
+		if within(block.node, n) {
+			text += len(block.text)
+			links += block.links
+		}
+	}
+	if text == 0 {
+		return 0
+	}
+	return float64(links) / float64(text)
+}
+
+func within(n, ancestor *html.Node) bool {
+	for curr := n; curr != nil; curr = curr.Parent {
+		if curr == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+func classAndID(n *html.Node) string {
+	class, _ := attrValue(n, "class")
+	id, _ := attrValue(n, "id")
+	return class + " " + id
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestMainBlocks(t *testing.T) {
+	article := "Goroutines are cheap, so a crawler can run one per host, park it on a rate limiter, and let the scheduler do the rest."
+	followUp := "Channels carry the discovered links back to the frontier, which keeps the breadth first order intact across workers."
+
+	testCases := []struct {
+		name     string
+		document string
+		expected []string
+	}{
+		{
+			name: "test case 1",
+			document: `<body><nav><a href="/">Home</a><a href="/blog">Blog</a></nav>
+<div class="cookie-banner">We use cookies to improve your experience on this site, accept them please.</div>
+<main><h1>Concurrency</h1><p>` + article + `</p><p>` + followUp + `</p></main>
+<footer><p>Copyright 2025 Example Incorporated, all rights reserved worldwide.</p></footer></body>`,
+			expected: []string{"Concurrency", article, followUp},
+		},
+		{
+			name: "test case 2",
+			document: `<body><div id="sidebar"><p>Popular posts from around the site, updated every single day.</p></div>
+<div class="links"><p><a href="/a">First related link title</a> <a href="/b">Second related link title</a></p></div>
+<div><p>` + article + `</p><p>` + followUp + `</p></div></body>`,
+			expected: []string{article, followUp},
+		},
+		{
+			name: "test case 3",
+			document: `<body><div class="post-content"><h2>Intro</h2><p>` + article + `</p></div>
+<div class="related"><p>` + followUp + `</p></div></body>`,
+			expected: []string{"Intro", article},
+		},
+		{
+			name:     "test case 4",
+			document: `<body><nav><p>only navigation here</p></nav></body>`,
+			expected: []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			htmlTree, err := html.Parse(strings.NewReader(testCase.document))
+			if err != nil {
+				t.Fatalf("%s failed, unexpected error: %v", testCase.name, err)
+			}
+			if result := extractContent(htmlTree).main; !reflect.DeepEqual(result, testCase.expected) {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	outcomeOK               = "ok"
+	outcomeNotModified      = "not_modified"
+	outcomeNotFound         = "not_found"
+	outcomeClientError      = "client_error"
+	outcomeRateLimited      = "rate_limited"
+	outcomeServerError      = "server_error"
+	outcomeNetwork          = "network"
+	outcomeDNS              = "dns"
+	outcomeTooManyRedirects = "too_many_redirects"
+	outcomeOutOfScope       = "redirect_out_of_scope"
+	outcomeDisallowed       = "redirect_disallowed"
+	outcomeNotHTML          = "not_html"
+	outcomeTooLarge         = "too_large"
+	outcomeNotArchived      = "not_archived"
+	outcomeOther            = "other"
+)
+
+type retryConfig struct {
+	attempts int           // retries after the first try, 0 disables retrying
+	base     time.Duration // first backoff, doubled on every retry
+	max      time.Duration // longest single wait, a longer Retry-After gives up instead
+}
+
+type fetchError struct { // a failed fetch, classified so the crawler knows whether to try again
+	status     int           // 0 when no response arrived
+	transient  bool          // 5xx, 429 and network errors may succeed later
+	retryAfter time.Duration // asked for by a 429 or 503
+	err        error
+}
+
+func (e *fetchError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fetchError) Unwrap() error {
+	return e.err
+}
+
+func classifyFetch(page fetchedPage, err error) (string, bool) { // the outcome class and whether it means the link is broken
+	var fetchErr *fetchError
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil && page.notModified:
+		return outcomeNotModified, false
+	case err == nil:
+		return outcomeOK, false
+	case errors.Is(err, errTooManyRedirects):
+		return outcomeTooManyRedirects, true
+	case errors.Is(err, errRedirectOutOfScope):
+		return outcomeOutOfScope, false
+	case errors.Is(err, errRedirectDisallowed):
+		return outcomeDisallowed, false
+	case errors.Is(err, errNotHTML):
+		return outcomeNotHTML, false
+	case errors.Is(err, errBodyTooLarge):
+		return outcomeTooLarge, false
+	case errors.Is(err, errNotArchived):
+		return outcomeNotArchived, false
+	case errors.As(err, &dnsErr):
+		return outcomeDNS, true
+	case errors.As(err, &fetchErr) && fetchErr.status == http.StatusTooManyRequests:
+		return outcomeRateLimited, false
+	case errors.As(err, &fetchErr) && (fetchErr.status == http.StatusNotFound || fetchErr.status == http.StatusGone):
+		return outcomeNotFound, true
+	case errors.As(err, &fetchErr) && 500 <= fetchErr.status:
+		return outcomeServerError, true
+	case errors.As(err, &fetchErr) && 400 <= fetchErr.status:
+		return outcomeClientError, true
+	case errors.As(err, &fetchErr):
+		return outcomeNetwork, true
+	}
+	return outcomeOther, false
+}
+
+func statusError(res *http.Response, now time.Time) error { // nil for responses worth parsing
+	switch {
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone:
+		return &fetchError{
+			status: res.StatusCode,
+			err:    errors.New("dead link"),
+		}
+	case res.StatusCode == http.StatusTooManyRequests:
+		return &fetchError{
+			status:     res.StatusCode,
+			transient:  true,
+			retryAfter: parseRetryAfter(res.Header.Get("Retry-After"), now),
+			err:        errors.New("rate limited"),
+		}
+	case 400 <= res.StatusCode && res.StatusCode < 500:
+		return &fetchError{
+			status: res.StatusCode,
+			err:    errors.New("client error"),
+		}
+	case 500 <= res.StatusCode: // error pages are never stored as content
+		fetchErr := &fetchError{
+			status:    res.StatusCode,
+			transient: true,
+			err:       errors.New("server error"),
+		}
+		if res.StatusCode == http.StatusServiceUnavailable {
+			fetchErr.retryAfter = parseRetryAfter(res.Header.Get("Retry-After"), now)
+		}
+		return fetchErr
+	}
+	return nil
+}
+
+func networkError(err error) error { // wraps transport failures, leaving cancellation and redirect policy errors alone
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errTooManyRedirects) || errors.Is(err, errRedirectOutOfScope) || errors.Is(err, errRedirectDisallowed) {
+		return err
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound { // the host doesn't exist, asking again won't help
+		return &fetchError{err: err}
+	} else if errors.Is(err, errNotArchived) {
+		return &fetchError{err: err}
+	}
+	return &fetchError{
+		transient: true,
+		err:       err,
+	}
+}
+
+func parseRetryAfter(value string, now time.Time) time.Duration { // either delay seconds or an http date
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(0, time.Duration(seconds)*time.Second)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(0, at.Sub(now))
+	}
+	return 0
+}
+
+func (r retryConfig) backoff(attempt int) time.Duration { // exponential with jitter, so retries from many workers spread out
+	wait := r.max
+	if attempt < 32 && r.base<<attempt < r.max {
+		wait = r.base << attempt
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + rand.N(wait/2+1)
+}
+
+func (c *crawlerConfig) fetch(ctx context.Context, rawCurrUrl string, prev validators, delay time.Duration) (fetchedPage, error) { // getHTML with retries, paced by the host limiter
+	currStruct, err := url.Parse(rawCurrUrl)
+	if err != nil {
+		return fetchedPage{}, err
+	}
+	host := currStruct.Host
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(ctx, host, delay); err != nil {
+			return fetchedPage{}, err
+		}
+		page, err := getHTML(ctx, c.fetcher, rawCurrUrl, prev, c.redirects, c.followRedirect(ctx), c.body)
+		var fetchErr *fetchError
+		if !errors.As(err, &fetchErr) || !fetchErr.transient {
+			c.limiter.succeeded(host) // the host answered, even if with a 404
+			return page, err
+		}
+		c.limiter.failed(host, fetchErr.retryAfter, time.Now())
+		if attempt >= c.retries.attempts || fetchErr.retryAfter > c.retries.max || ctx.Err() != nil {
+			return page, err
+		}
+
+		wait := max(c.retries.backoff(attempt), fetchErr.retryAfter)
+		log.Printf("retrying %s in %v: %v", rawCurrUrl, wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return page, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
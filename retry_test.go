@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{
+			name:     "test case 1",
+			value:    "120",
+			expected: 2 * time.Minute,
+		},
+		{
+			name:     "test case 2",
+			value:    "Wed, 01 Jan 2025 00:00:30 GMT",
+			expected: 30 * time.Second,
+		},
+		{
+			name:     "test case 3",
+			value:    "Tue, 31 Dec 2024 00:00:00 GMT",
+			expected: 0,
+		},
+		{
+			name:     "test case 4",
+			value:    "soon",
+			expected: 0,
+		},
+		{
+			name:     "test case 5",
+			value:    "",
+			expected: 0,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if result := parseRetryAfter(testCase.value, now); result != testCase.expected {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	testCases := []struct {
+		name       string
+		status     int
+		retryAfter string
+		expected   *fetchError // nil when the page should be parsed
+	}{
+		{
+			name:     "test case 1",
+			status:   http.StatusOK,
+			expected: nil,
+		},
+		{
+			name:     "test case 2",
+			status:   http.StatusNotFound,
+			expected: &fetchError{status: http.StatusNotFound},
+		},
+		{
+			name:       "test case 3",
+			status:     http.StatusTooManyRequests,
+			retryAfter: "5",
+			expected:   &fetchError{status: http.StatusTooManyRequests, transient: true, retryAfter: 5 * time.Second},
+		},
+		{
+			name:     "test case 4",
+			status:   http.StatusInternalServerError,
+			expected: &fetchError{status: http.StatusInternalServerError, transient: true},
+		},
+		{
+			name:       "test case 5",
+			status:     http.StatusServiceUnavailable,
+			retryAfter: "5",
+			expected:   &fetchError{status: http.StatusServiceUnavailable, transient: true, retryAfter: 5 * time.Second},
+		},
+		{
+			name:       "test case 6",
+			status:     http.StatusBadGateway,
+			retryAfter: "5",
+			expected:   &fetchError{status: http.StatusBadGateway, transient: true},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			res := &http.Response{
+				StatusCode: testCase.status,
+				Header:     http.Header{},
+			}
+			if testCase.retryAfter != "" {
+				res.Header.Set("Retry-After", testCase.retryAfter)
+			}
+			err := statusError(res, time.Now())
+			if testCase.expected == nil {
+				if err != nil {
+					t.Errorf("%s failed, unexpected error: %v", testCase.name, err)
+				}
+				return
+			}
+			result, ok := err.(*fetchError)
+			if !ok {
+				t.Errorf("%s failed, %v is not a fetch error", testCase.name, err)
+			} else if result.status != testCase.expected.status || result.transient != testCase.expected.transient || result.retryAfter != testCase.expected.retryAfter {
+				t.Errorf("%s failed, %+v != %+v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	retries := retryConfig{
+		base: 100 * time.Millisecond,
+		max:  time.Second,
+	}
+
+	testCases := []struct {
+		name    string
+		attempt int
+		ceiling time.Duration // jitter keeps waits within [ceiling/2, ceiling]
+	}{
+		{
+			name:    "test case 1",
+			attempt: 0,
+			ceiling: 100 * time.Millisecond,
+		},
+		{
+			name:    "test case 2",
+			attempt: 2,
+			ceiling: 400 * time.Millisecond,
+		},
+		{
+			name:    "test case 3",
+			attempt: 10,
+			ceiling: time.Second,
+		},
+		{
+			name:    "test case 4",
+			attempt: 100,
+			ceiling: time.Second,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			for range 100 {
+				if result := retries.backoff(testCase.attempt); result < testCase.ceiling/2 || result > testCase.ceiling {
+					t.Errorf("%s failed, %v not within [%v, %v]", testCase.name, result, testCase.ceiling/2, testCase.ceiling)
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestClassifyFetch(t *testing.T) {
+	testCases := []struct {
+		name   string
+		page   fetchedPage
+		err    error
+		class  string
+		broken bool
+	}{
+		{
+			name:  "test case 1",
+			page:  fetchedPage{status: http.StatusOK},
+			class: outcomeOK,
+		},
+		{
+			name:  "test case 2",
+			page:  fetchedPage{status: http.StatusNotModified, notModified: true},
+			class: outcomeNotModified,
+		},
+		{
+			name:   "test case 3",
+			err:    &fetchError{status: http.StatusGone, err: errors.New("dead link")},
+			class:  outcomeNotFound,
+			broken: true,
+		},
+		{
+			name:   "test case 4",
+			err:    &fetchError{status: http.StatusForbidden, err: errors.New("client error")},
+			class:  outcomeClientError,
+			broken: true,
+		},
+		{
+			name:  "test case 5",
+			err:   &fetchError{status: http.StatusTooManyRequests, transient: true, err: errors.New("rate limited")},
+			class: outcomeRateLimited,
+		},
+		{
+			name:   "test case 6",
+			err:    &fetchError{status: http.StatusBadGateway, transient: true, err: errors.New("server error")},
+			class:  outcomeServerError,
+			broken: true,
+		},
+		{
+			name:   "test case 7",
+			err:    networkError(&net.DNSError{Err: "no such host", Name: "nope.invalid", IsNotFound: true}),
+			class:  outcomeDNS,
+			broken: true,
+		},
+		{
+			name:   "test case 8",
+			err:    networkError(errors.New("connection reset by peer")),
+			class:  outcomeNetwork,
+			broken: true,
+		},
+		{
+			name:   "test case 9",
+			err:    errTooManyRedirects,
+			class:  outcomeTooManyRedirects,
+			broken: true,
+		},
+		{
+			name:  "test case 10",
+			err:   errRedirectOutOfScope,
+			class: outcomeOutOfScope,
+		},
+		{
+			name:  "test case 11",
+			err:   errNotHTML,
+			class: outcomeNotHTML,
+		},
+		{
+			name:  "test case 12",
+			err:   errRedirectDisallowed,
+			class: outcomeDisallowed,
+		},
+		{
+			name:  "test case 13",
+			err:   errors.New("something else"),
+			class: outcomeOther,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			class, broken := classifyFetch(testCase.page, testCase.err)
+			if class != testCase.class || broken != testCase.broken {
+				t.Errorf("%s failed, %v %v != %v %v", testCase.name, class, broken, testCase.class, testCase.broken)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	robotsTTL       = 24 * time.Hour
+	robotsTimeout   = 10 * time.Second
+	robotsRedirects = 5 // rfc 9309 asks crawlers to follow at least five
+)
+
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	sitemaps   []string
+}
+
+type robotsEntry struct {
+	ready     chan struct{} // closed once rules have been fetched
+	rules     robotsRules
+	fetchedAt time.Time
+}
+
+type robotsCache struct {
+	mu      *sync.Mutex
+	entries map[string]*robotsEntry
+	fetcher fetcher
+	agent   string // product token matched against user-agent lines
+}
+
+func parseRobots(body, agent string) robotsRules {
+	type group struct {
+		agents []string
+		rules  []robotsRule
+		delay  time.Duration
+	}
+
+	groups := []*group{}
+	sitemaps := []string{}
+	var curr *group
+	inAgents := false // consecutive user-agent lines share a group
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				curr = &group{}
+				groups = append(groups, curr)
+			}
+			curr.agents = append(curr.agents, strings.ToLower(value))
+			inAgents = true
+		case "allow", "disallow":
+			inAgents = false
+			if curr == nil || value == "" { // an empty disallow allows everything
+				continue
+			}
+			curr.rules = append(curr.rules, robotsRule{
+				pattern: value,
+				allow:   key == "allow",
+			})
+		case "crawl-delay":
+			inAgents = false
+			if curr == nil {
+				continue
+			}
+			if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+				curr.delay = time.Duration(secs * float64(time.Second))
+			}
+		case "sitemap": // not tied to any group
+			if value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+		default:
+			inAgents = false
+		}
+	}
+
+	// groups naming our product token as a whole win over the wildcard group, groups with the same name are merged
+	agent = strings.ToLower(agent)
+	matched := []*group{}
+	wildcard := []*group{}
+	for _, g := range groups {
+		if slices.Contains(g.agents, agent) {
+			matched = append(matched, g)
+		} else if slices.Contains(g.agents, "*") {
+			wildcard = append(wildcard, g)
+		}
+	}
+	if len(matched) == 0 {
+		matched = wildcard
+	}
+
+	res := robotsRules{
+		sitemaps: sitemaps,
+	}
+	for _, g := range matched {
+		res.rules = append(res.rules, g.rules...)
+		if g.delay > res.crawlDelay {
+			res.crawlDelay = g.delay
+		}
+	}
+	return res
+}
+
+func robotsMatch(pattern, path string) bool { // * matches any sequence, a trailing $ anchors the end
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(rest, last)
+	}
+	return strings.Contains(rest, last)
+}
+
+func (r robotsRules) allowed(urlStruct *url.URL) bool {
+	path := urlStruct.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if urlStruct.RawQuery != "" {
+		path += "?" + urlStruct.RawQuery
+	}
+
+	allow := true
+	longest := -1
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		length := len(strings.TrimSuffix(rule.pattern, "$"))
+		if length > longest || (length == longest && rule.allow) { // longest match wins, allow wins ties
+			longest = length
+			allow = rule.allow
+		}
+	}
+	return allow
+}
+
+func productToken(agent string) string { // "rumbling/1.0 (+https://...)" is addressed as "rumbling" by robots.txt and robots meta tags
+	fields := strings.Fields(agent)
+	if len(fields) == 0 {
+		return crawlerName
+	}
+	token, _, _ := strings.Cut(fields[0], "/")
+	if token == "" {
+		return crawlerName
+	}
+	return strings.ToLower(token)
+}
+
+func fetchRobots(ctx context.Context, f fetcher, urlStruct *url.URL, agent string) robotsRules {
+	robotsUrl := &url.URL{
+		Scheme: urlStruct.Scheme,
+		Host:   urlStruct.Host,
+		Path:   "/robots.txt",
+	}
+	ctx, cancel := context.WithTimeout(ctx, robotsTimeout)
+	defer cancel()
+
+	res, _, err := followRedirects(ctx, f, robotsUrl.String(), nil, redirectConfig{limit: robotsRedirects}, nil)
+	if err != nil { // unreachable, assume everything is disallowed
+		return robotsRules{rules: []robotsRule{{pattern: "/"}}}
+	}
+	defer res.Body.Close()
+
+	if 500 <= res.StatusCode {
+		return robotsRules{rules: []robotsRule{{pattern: "/"}}}
+	} else if 400 <= res.StatusCode { // no robots.txt, everything is allowed
+		return robotsRules{}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 500*1024))
+	if err != nil {
+		return robotsRules{rules: []robotsRule{{pattern: "/"}}}
+	}
+	return parseRobots(string(body), agent)
+}
+
+func (r *robotsCache) rulesFor(ctx context.Context, urlStruct *url.URL) (robotsRules, error) {
+	key := urlStruct.Scheme + "://" + urlStruct.Host
+
+	for {
+		r.mu.Lock()
+		entry, ok := r.entries[key]
+		if ok && entry.fetchedAt.IsZero() { // another crawl is fetching it
+			r.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return robotsRules{}, ctx.Err()
+			case <-entry.ready:
+			}
+			continue // the other fetch may have been cancelled
+		} else if ok && time.Since(entry.fetchedAt) < robotsTTL {
+			r.mu.Unlock()
+			return entry.rules, nil
+		}
+		entry = &robotsEntry{
+			ready: make(chan struct{}),
+		}
+		r.entries[key] = entry
+		r.mu.Unlock()
+
+		rules := fetchRobots(ctx, r.fetcher, urlStruct, r.agent)
+
+		r.mu.Lock()
+		if ctx.Err() != nil { // don't cache the failure of a cancelled fetch
+			delete(r.entries, key)
+			r.mu.Unlock()
+			close(entry.ready)
+			return robotsRules{}, ctx.Err()
+		}
+		entry.rules = rules
+		entry.fetchedAt = time.Now()
+		r.mu.Unlock()
+		close(entry.ready)
+		return rules, nil
+	}
+}
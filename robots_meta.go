@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var valuedDirectives = []string{"unavailable_after", "max-snippet", "max-image-preview", "max-video-preview"} // directives that carry their own colon
+
+type robotsDirectives struct {
+	noindex  bool // the page is not stored
+	nofollow bool // none of the page's links are queued
+}
+
+type directivesConfig struct {
+	ignoreHosts []string // canonical host patterns, like scope entries, whose directives an operator chose to ignore
+	agent       string   // product token that bot specific directives have to name
+}
+
+func (d directivesConfig) ignores(normUrl string) bool { // internal sites often carry noindex to stay out of public search engines
+	urlStruct, err := url.Parse(normUrl)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range d.ignoreHosts {
+		if hostMatches(pattern, urlStruct) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r robotsDirectives) merge(other robotsDirectives) robotsDirectives { // the most restrictive directive wins
+	return robotsDirectives{
+		noindex:  r.noindex || other.noindex,
+		nofollow: r.nofollow || other.nofollow,
+	}
+}
+
+func parseDirectives(value string) robotsDirectives {
+	directives := robotsDirectives{}
+	for _, token := range strings.Split(strings.ToLower(value), ",") {
+		switch strings.TrimSpace(token) {
+		case "noindex":
+			directives.noindex = true
+		case "nofollow":
+			directives.nofollow = true
+		case "none":
+			directives.noindex = true
+			directives.nofollow = true
+		}
+	}
+	return directives
+}
+
+func headerDirectives(values []string, agent string) robotsDirectives { // X-Robots-Tag values, optionally prefixed with the crawler they address
+	directives := robotsDirectives{}
+	for _, value := range values {
+		if name, rest, ok := strings.Cut(value, ":"); ok {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if !strings.ContainsAny(name, ", ") && !slices.Contains(valuedDirectives, name) {
+				if name != strings.ToLower(agent) {
+					continue // meant for another crawler
+				}
+				value = rest
+			}
+		}
+		directives = directives.merge(parseDirectives(value))
+	}
+	return directives
+}
+
+func metaDirectives(htmlTree *html.Node, agent string) robotsDirectives { // <meta name="robots"> and ones naming this crawler
+	directives := robotsDirectives{}
+	for n := range htmlTree.Descendants() {
+		if n.Type != html.ElementNode || n.DataAtom != atom.Meta {
+			continue
+		}
+		name, _ := attrValue(n, "name")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "robots" && name != strings.ToLower(agent) {
+			continue
+		}
+		content, _ := attrValue(n, "content")
+		directives = directives.merge(parseDirectives(content))
+	}
+	return directives
+}
+
+func followable(links []pageLink) []pageLink { // drops anchors marked rel="nofollow"
+	res := []pageLink{}
+	for _, link := range links {
+		if !isNofollow(link.rel) {
+			res = append(res, link)
+		}
+	}
+	return res
+}
+
+func isNofollow(rel string) bool {
+	return slices.Contains(strings.Fields(rel), "nofollow")
+}
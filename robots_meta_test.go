@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestHeaderDirectives(t *testing.T) {
+	testCases := []struct {
+		name     string
+		values   []string
+		agent    string
+		expected robotsDirectives
+	}{
+		{
+			name:     "test case 1",
+			agent:    "rumbling",
+			values:   nil,
+			expected: robotsDirectives{},
+		},
+		{
+			name:     "test case 2",
+			agent:    "rumbling",
+			values:   []string{"noindex, nofollow"},
+			expected: robotsDirectives{noindex: true, nofollow: true},
+		},
+		{
+			name:     "test case 3",
+			agent:    "rumbling",
+			values:   []string{"NoIndex", "unavailable_after: 25 Jun 2010 15:00:00 PST"},
+			expected: robotsDirectives{noindex: true},
+		},
+		{
+			name:     "test case 4",
+			agent:    "rumbling",
+			values:   []string{"googlebot: none", "rumbling: nofollow"},
+			expected: robotsDirectives{nofollow: true},
+		},
+		{
+			name:     "test case 5",
+			agent:    "rumbling",
+			values:   []string{"none"},
+			expected: robotsDirectives{noindex: true, nofollow: true},
+		},
+		{
+			name:     "test case 6",
+			agent:    "examplebot",
+			values:   []string{"rumbling: noindex", "ExampleBot: nofollow"},
+			expected: robotsDirectives{nofollow: true},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if result := headerDirectives(testCase.values, testCase.agent); result != testCase.expected {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestMetaDirectives(t *testing.T) {
+	testCases := []struct {
+		name     string
+		document string
+		agent    string
+		expected robotsDirectives
+	}{
+		{
+			name:     "test case 1",
+			agent:    "rumbling",
+			document: `<html><head><title>plain</title></head></html>`,
+			expected: robotsDirectives{},
+		},
+		{
+			name:     "test case 2",
+			agent:    "rumbling",
+			document: `<html><head><meta name="robots" content="noindex,nofollow"></head></html>`,
+			expected: robotsDirectives{noindex: true, nofollow: true},
+		},
+		{
+			name:     "test case 3",
+			agent:    "rumbling",
+			document: `<html><head><meta name="Rumbling" content="noindex"><meta name="googlebot" content="nofollow"></head></html>`,
+			expected: robotsDirectives{noindex: true},
+		},
+		{
+			name:     "test case 4",
+			agent:    "rumbling",
+			document: `<html><head><meta name="description" content="noindex"></head></html>`,
+			expected: robotsDirectives{},
+		},
+		{
+			name:     "test case 5",
+			agent:    "examplebot",
+			document: `<html><head><meta name="rumbling" content="noindex"><meta name="examplebot" content="nofollow"></head></html>`,
+			expected: robotsDirectives{nofollow: true},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			htmlTree, err := html.Parse(strings.NewReader(testCase.document))
+			if err != nil {
+				t.Fatalf("%s failed, unexpected error: %v", testCase.name, err)
+			}
+			if result := metaDirectives(htmlTree, testCase.agent); result != testCase.expected {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
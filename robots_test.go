@@ -0,0 +1,232 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRobotsMatch(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{
+			name:     "test case 1",
+			pattern:  "/fish",
+			path:     "/fish.html",
+			expected: true,
+		},
+		{
+			name:     "test case 2",
+			pattern:  "/fish",
+			path:     "/Fish.asp",
+			expected: false,
+		},
+		{
+			name:     "test case 3",
+			pattern:  "/*.php",
+			path:     "/folder/index.php?id=1",
+			expected: true,
+		},
+		{
+			name:     "test case 4",
+			pattern:  "/*.php$",
+			path:     "/folder/index.php?id=1",
+			expected: false,
+		},
+		{
+			name:     "test case 5",
+			pattern:  "/*.php$",
+			path:     "/folder/index.php",
+			expected: true,
+		},
+		{
+			name:     "test case 6",
+			pattern:  "/fish*.php",
+			path:     "/fishheads/catfish.php?parameters",
+			expected: true,
+		},
+		{
+			name:     "test case 7",
+			pattern:  "/",
+			path:     "/anything",
+			expected: true,
+		},
+		{
+			name:     "test case 8",
+			pattern:  "/docs/$",
+			path:     "/docs/intro",
+			expected: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if result := robotsMatch(testCase.pattern, testCase.path); result != testCase.expected {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		expected robotsRules
+	}{
+		{
+			name: "test case 1",
+			body: "User-agent: *\nDisallow: /private\nAllow: /private/open\n",
+			expected: robotsRules{
+				rules: []robotsRule{
+					{pattern: "/private", allow: false},
+					{pattern: "/private/open", allow: true},
+				},
+				sitemaps: []string{},
+			},
+		},
+		{
+			name: "test case 2",
+			body: "User-agent: *\nDisallow: /\n\nUser-agent: Rumbling\nDisallow: /admin\nCrawl-delay: 2\n",
+			expected: robotsRules{
+				rules: []robotsRule{
+					{pattern: "/admin", allow: false},
+				},
+				crawlDelay: 2 * time.Second,
+				sitemaps:   []string{},
+			},
+		},
+		{
+			name: "test case 3",
+			body: "# comment\nUser-agent: googlebot\nUser-agent: rumbling\nDisallow: /a # trailing\n\nUser-agent: rumbling\nDisallow: /b\nSitemap: https://example.com/sitemap.xml\n",
+			expected: robotsRules{
+				rules: []robotsRule{
+					{pattern: "/a", allow: false},
+					{pattern: "/b", allow: false},
+				},
+				sitemaps: []string{"https://example.com/sitemap.xml"},
+			},
+		},
+		{
+			name: "test case 4",
+			body: "User-agent: googlebot\nDisallow: /\n\nUser-agent: *\nDisallow:\n",
+			expected: robotsRules{
+				sitemaps: []string{},
+			},
+		},
+		{
+			name: "test case 5",
+			body: "User-agent: ling\nDisallow: /a\n\nUser-agent: rumblingbot\nDisallow: /b\n\nUser-agent: *\nDisallow: /c\n",
+			expected: robotsRules{
+				rules: []robotsRule{
+					{pattern: "/c", allow: false},
+				},
+				sitemaps: []string{},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := parseRobots(testCase.body, crawlerName)
+			if comp := reflect.DeepEqual(result, testCase.expected); !comp {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	rules := parseRobots("User-agent: *\nDisallow: /docs\nAllow: /docs/public\nDisallow: /*.pdf$\nAllow: /page\nDisallow: /page\n", crawlerName)
+
+	testCases := []struct {
+		name     string
+		url      string
+		expected bool
+	}{
+		{
+			name:     "test case 1",
+			url:      "https://example.com/",
+			expected: true,
+		},
+		{
+			name:     "test case 2",
+			url:      "https://example.com/docs/guide",
+			expected: false,
+		},
+		{
+			name:     "test case 3",
+			url:      "https://example.com/docs/public/guide",
+			expected: true,
+		},
+		{
+			name:     "test case 4",
+			url:      "https://example.com/files/report.pdf",
+			expected: false,
+		},
+		{
+			name:     "test case 5",
+			url:      "https://example.com/files/report.pdf?download=1",
+			expected: true,
+		},
+		{
+			name:     "test case 6",
+			url:      "https://example.com/page",
+			expected: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			urlStruct, err := url.Parse(testCase.url)
+			if err != nil {
+				t.Fatalf("%s failed, unexpected error: %v", testCase.name, err)
+			}
+			if result := rules.allowed(urlStruct); result != testCase.expected {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestProductToken(t *testing.T) {
+	testCases := []struct {
+		name     string
+		agent    string
+		expected string
+	}{
+		{
+			name:     "test case 1",
+			agent:    userAgent,
+			expected: "rumbling",
+		},
+		{
+			name:     "test case 2",
+			agent:    "ExampleBot/2.1 (+https://example.com/bot)",
+			expected: "examplebot",
+		},
+		{
+			name:     "test case 3",
+			agent:    "examplebot",
+			expected: "examplebot",
+		},
+		{
+			name:     "test case 4",
+			agent:    "  ",
+			expected: crawlerName,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if result := productToken(testCase.agent); result != testCase.expected {
+				t.Errorf("%s failed, %v != %v", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
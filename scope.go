@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type scope struct { // decides which discovered urls a crawl may fetch
+	hosts    []string // canonical hosts, a leading *. matches any subdomain but not the domain itself
+	prefixes []string // unescaped path prefixes, empty allows every path
+	include  []*regexp.Regexp
+	exclude  []*regexp.Regexp
+	opts     crawlOptions // robots.txt style include and exclude patterns
+}
+
+type scopeDecision struct {
+	inScope bool
+	reason  string
+	offHost bool // rejected for its host, true of most external links so not worth counting as a skip
+}
+
+func newScope(seedUrl string, opts crawlOptions, canon canonicalizer) (scope, error) {
+	seed, err := canon.canonicalize(seedUrl)
+	if err != nil {
+		return scope{}, err
+	}
+	seedStruct, err := url.Parse(seed)
+	if err != nil {
+		return scope{}, err
+	}
+
+	s := scope{
+		hosts: []string{seedStruct.Host}, // the seed's host is always in scope, or the crawl could never start
+		opts:  opts,
+	}
+	for _, prefix := range opts.PathPrefixes {
+		if unescaped, err := url.PathUnescape(prefix); err == nil { // paths are compared unescaped
+			prefix = unescaped
+		}
+		s.prefixes = append(s.prefixes, prefix)
+	}
+	for _, entry := range opts.AllowedHosts {
+		host, err := scopeHost(entry, canon)
+		if err != nil {
+			return scope{}, err
+		}
+		s.hosts = append(s.hosts, host)
+	}
+	for _, pattern := range opts.IncludeRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return scope{}, err
+		}
+		s.include = append(s.include, re)
+	}
+	for _, pattern := range opts.ExcludeRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return scope{}, err
+		}
+		s.exclude = append(s.exclude, re)
+	}
+	return s, nil
+}
+
+func scopeHost(entry string, canon canonicalizer) (string, error) { // canonicalizes the host part so entries compare like crawled urls
+	name, wildcard := strings.CutPrefix(strings.TrimSpace(entry), "*.")
+	if name == "" || strings.ContainsAny(name, "/*?#") {
+		return "", errors.New("allowed host " + entry + " is not a host")
+	}
+	entryStruct, err := url.Parse("http://" + name)
+	if err != nil {
+		return "", errors.New("allowed host " + entry + " is not a host")
+	}
+	port := entryStruct.Port()
+	name = strings.TrimSuffix(name, ":"+port) // kept as written, canonicalizing would drop :80 and keep :443
+	normUrl, err := canon.canonicalize("http://" + name)
+	if err != nil {
+		return "", err
+	}
+	normStruct, err := url.Parse(normUrl)
+	if err != nil {
+		return "", err
+	}
+	host := normStruct.Host
+	if port != "" {
+		host += ":" + port
+	}
+	if wildcard {
+		return "*." + host, nil
+	}
+	return host, nil
+}
+
+func splitPort(host string) (string, string) { // "example.com:8080" or "[::1]:8080", the port is empty when none is named
+	i := strings.LastIndex(host, ":")
+	if i < 0 || i < strings.LastIndex(host, "]") {
+		return host, ""
+	}
+	return host[:i], host[i+1:]
+}
+
+func hostMatches(pattern string, urlStruct *url.URL) bool {
+	pattern, port := splitPort(pattern)
+	host, urlPort := splitPort(urlStruct.Host)
+	if port != "" { // the entry names a port, canonical urls leave out the default one for their scheme
+		if urlPort == "" && urlStruct.Scheme == "https" {
+			urlPort = "443"
+		} else if urlPort == "" {
+			urlPort = "80"
+		}
+		if urlPort != port {
+			return false
+		}
+	}
+	if base, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+base)
+	}
+	return host == pattern
+}
+
+func underPrefix(path, prefix string) bool { // "/docs" covers "/docs" and "/docs/a" but not "/docs-old"
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return strings.HasSuffix(prefix, "/") || len(path) == len(prefix) || path[len(prefix)] == '/'
+}
+
+func (s scope) check(normUrl string) scopeDecision { // rules run from broadest to narrowest, the first rejection is the reason
+	urlStruct, err := url.Parse(normUrl)
+	if err != nil {
+		return scopeDecision{reason: "unparsable url"}
+	}
+
+	reasons := []string{}
+	matched := ""
+	for _, pattern := range s.hosts {
+		if hostMatches(pattern, urlStruct) {
+			matched = pattern
+			break
+		}
+	}
+	if matched == "" {
+		return scopeDecision{
+			reason:  "host " + urlStruct.Host + " not allowed",
+			offHost: true,
+		}
+	}
+	reasons = append(reasons, "host matches "+matched)
+
+	path := urlStruct.Path
+	if path == "" {
+		path = "/"
+	}
+	if len(s.prefixes) != 0 {
+		under := ""
+		for _, prefix := range s.prefixes {
+			if underPrefix(path, prefix) {
+				under = prefix
+				break
+			}
+		}
+		if under == "" {
+			return scopeDecision{reason: "path not under " + strings.Join(s.prefixes, ", ")}
+		}
+		reasons = append(reasons, "path under "+under)
+	}
+
+	if ok, reason := s.opts.pathAllowed(urlStruct); !ok {
+		return scopeDecision{reason: reason}
+	}
+
+	for _, re := range s.exclude {
+		if re.MatchString(normUrl) {
+			return scopeDecision{reason: "excluded by regex " + re.String()}
+		}
+	}
+	if len(s.include) != 0 {
+		included := ""
+		for _, re := range s.include {
+			if re.MatchString(normUrl) {
+				included = re.String()
+				break
+			}
+		}
+		if included == "" {
+			return scopeDecision{reason: "not matched by any include regex"}
+		}
+		reasons = append(reasons, "matches regex "+included)
+	}
+
+	return scopeDecision{
+		inScope: true,
+		reason:  strings.Join(reasons, ", "),
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScopeCheck(t *testing.T) {
+	s, err := newScope("https://www.example.com/", crawlOptions{
+		AllowedHosts: []string{"docs.example.com", "*.blog.example.com", "localhost:8080"},
+		PathPrefixes: []string{"/docs/", "/posts/"},
+		Exclude:      []string{"/docs/v1/"},
+		IncludeRegex: []string{`/(docs|posts)/[a-z-]+`},
+		ExcludeRegex: []string{`\.pdf$`},
+	}, defaultCanonicalizer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name            string
+		url             string
+		expectedInScope bool
+		expectedReason  string
+	}{
+		{
+			name:            "test case 1",
+			url:             "https://www.example.com/docs/intro",
+			expectedInScope: true,
+			expectedReason:  "host matches www.example.com, path under /docs/, matches regex /(docs|posts)/[a-z-]+",
+		},
+		{
+			name:            "test case 2",
+			url:             "https://docs.example.com/posts/hello",
+			expectedInScope: true,
+			expectedReason:  "host matches docs.example.com, path under /posts/, matches regex /(docs|posts)/[a-z-]+",
+		},
+		{
+			name:            "test case 3",
+			url:             "https://eng.blog.example.com/posts/hello",
+			expectedInScope: true,
+			expectedReason:  "host matches *.blog.example.com, path under /posts/, matches regex /(docs|posts)/[a-z-]+",
+		},
+		{
+			name:            "test case 4",
+			url:             "https://blog.example.com/posts/hello",
+			expectedInScope: false,
+			expectedReason:  "host blog.example.com not allowed",
+		},
+		{
+			name:            "test case 5",
+			url:             "http://localhost:8081/docs/intro",
+			expectedInScope: false,
+			expectedReason:  "host localhost:8081 not allowed",
+		},
+		{
+			name:            "test case 6",
+			url:             "https://www.example.com/about",
+			expectedInScope: false,
+			expectedReason:  "path not under /docs/, /posts/",
+		},
+		{
+			name:            "test case 7",
+			url:             "https://www.example.com/docs/v1/intro",
+			expectedInScope: false,
+			expectedReason:  "excluded by pattern /docs/v1/",
+		},
+		{
+			name:            "test case 8",
+			url:             "https://www.example.com/docs/guide.pdf",
+			expectedInScope: false,
+			expectedReason:  `excluded by regex \.pdf$`,
+		},
+		{
+			name:            "test case 9",
+			url:             "https://www.example.com/docs/2024",
+			expectedInScope: false,
+			expectedReason:  "not matched by any include regex",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := s.check(testCase.url)
+			if result.inScope != testCase.expectedInScope || result.reason != testCase.expectedReason {
+				t.Errorf("%s failed, (%v, %s) != (%v, %s)", testCase.name, result.inScope, result.reason, testCase.expectedInScope, testCase.expectedReason)
+			}
+		})
+	}
+}
+
+func TestScopeBoundaries(t *testing.T) {
+	s, err := newScope("https://www.example.com/", crawlOptions{
+		AllowedHosts: []string{"*.example.org:443", "example.net:80", "example.edu:8080"},
+		PathPrefixes: []string{"/docs", "/caf%C3%A9/"},
+	}, defaultCanonicalizer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name            string
+		url             string
+		expectedInScope bool
+		expectedReason  string
+	}{
+		{
+			name:            "test case 1",
+			url:             "https://a.example.org/docs",
+			expectedInScope: true,
+			expectedReason:  "host matches *.example.org:443, path under /docs",
+		},
+		{
+			name:            "test case 2",
+			url:             "http://a.example.org/docs",
+			expectedInScope: false,
+			expectedReason:  "host a.example.org not allowed",
+		},
+		{
+			name:            "test case 3",
+			url:             "http://example.net/docs/intro",
+			expectedInScope: true,
+			expectedReason:  "host matches example.net:80, path under /docs",
+		},
+		{
+			name:            "test case 4",
+			url:             "https://example.net/docs/intro",
+			expectedInScope: false,
+			expectedReason:  "host example.net not allowed",
+		},
+		{
+			name:            "test case 5",
+			url:             "http://example.edu:8080/docs",
+			expectedInScope: true,
+			expectedReason:  "host matches example.edu:8080, path under /docs",
+		},
+		{
+			name:            "test case 6",
+			url:             "https://www.example.com/docs-old/intro",
+			expectedInScope: false,
+			expectedReason:  "path not under /docs, /café/",
+		},
+		{
+			name:            "test case 7",
+			url:             "https://www.example.com/caf%C3%A9/menu",
+			expectedInScope: true,
+			expectedReason:  "host matches www.example.com, path under /café/",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			result := s.check(testCase.url)
+			if result.inScope != testCase.expectedInScope || result.reason != testCase.expectedReason {
+				t.Errorf("%s failed, (%v, %s) != (%v, %s)", testCase.name, result.inScope, result.reason, testCase.expectedInScope, testCase.expectedReason)
+			}
+		})
+	}
+}
+
+func TestNewScope(t *testing.T) {
+	testCases := []struct {
+		name          string
+		opts          crawlOptions
+		expectedHosts []string
+		errorPresent  bool
+	}{
+		{
+			name:          "test case 1",
+			opts:          crawlOptions{},
+			expectedHosts: []string{"www.example.com"},
+			errorPresent:  false,
+		},
+		{
+			name:          "test case 2",
+			opts:          crawlOptions{AllowedHosts: []string{"Docs.Example.com", "*.example.org:443"}},
+			expectedHosts: []string{"www.example.com", "docs.example.com", "*.example.org:443"},
+			errorPresent:  false,
+		},
+		{
+			name:         "test case 3",
+			opts:         crawlOptions{AllowedHosts: []string{"example.com/docs"}},
+			errorPresent: true,
+		},
+		{
+			name:         "test case 4",
+			opts:         crawlOptions{AllowedHosts: []string{"*."}},
+			errorPresent: true,
+		},
+		{
+			name:          "test case 5",
+			opts:          crawlOptions{AllowedHosts: []string{"Example.NET:80", "[::1]:8080"}},
+			expectedHosts: []string{"www.example.com", "example.net:80", "[::1]:8080"},
+			errorPresent:  false,
+		},
+		{
+			name:         "test case 6",
+			opts:         crawlOptions{AllowedHosts: []string{"example.com:http"}},
+			errorPresent: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			s, err := newScope("https://www.example.com/", testCase.opts, defaultCanonicalizer)
+			if (err != nil) != testCase.errorPresent {
+				t.Errorf("%s failed, expecting err = %v", testCase.name, err)
+			} else if err == nil && strings.Join(s.hosts, " ") != strings.Join(testCase.expectedHosts, " ") {
+				t.Errorf("%s failed, %v != %v", testCase.name, s.hosts, testCase.expectedHosts)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+)
+
+const (
+	shingleSize     = 3  // words per feature, so reordered sentences still change the hash
+	minSimhashWords = 10 // shorter pages share too few features to compare reliably
+)
+
+const (
+	dedupLink = "link" // store near-duplicates, pointing them at their canonical
+	dedupSkip = "skip" // don't store near-duplicates at all
+)
+
+type dedupConfig struct {
+	mode     string
+	distance int // most differing bits for two pages to count as near-duplicates, negative disables detection
+}
+
+type fingerprint struct {
+	url  string
+	hash uint64
+}
+
+func simhash(content string) uint64 {
+	words := strings.Fields(content)
+	features := []string{}
+	if len(words) < shingleSize {
+		features = words
+	}
+	for i := 0; i+shingleSize <= len(words); i++ {
+		features = append(features, strings.Join(words[i:i+shingleSize], " "))
+	}
+
+	weights := [64]int{}
+	for _, feature := range features {
+		hasher := fnv.New64a()
+		hasher.Write([]byte(feature))
+		sum := hasher.Sum64()
+		for bit := range 64 {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var hash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+func hammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+func (c *crawlerConfig) matchFingerprint(normCurrUrl string, hash uint64, words int) (string, int, bool) { // finds a near-duplicate or registers the page as a canonical, in one step so concurrent workers agree
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dedup.distance < 0 || words < minSimhashWords {
+		return "", 0, false
+	}
+
+	best := ""
+	bestDistance := c.dedup.distance + 1
+	known := false
+	for _, fp := range c.fingerprints {
+		if fp.url == normCurrUrl { // a re-crawl of the same page
+			known = true
+			continue
+		}
+		if distance := hammingDistance(fp.hash, hash); distance < bestDistance {
+			best = fp.url
+			bestDistance = distance
+		}
+	}
+	if best != "" {
+		return best, bestDistance, true
+	} else if known {
+		return "", 0, false
+	}
+
+	c.fingerprints = append(c.fingerprints, fingerprint{
+		url:  normCurrUrl,
+		hash: hash,
+	})
+	return "", 0, false
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestHammingDistance(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a        uint64
+		b        uint64
+		expected int
+	}{
+		{
+			name:     "test case 1",
+			a:        0,
+			b:        0,
+			expected: 0,
+		},
+		{
+			name:     "test case 2",
+			a:        0b1011,
+			b:        0b0010,
+			expected: 2,
+		},
+		{
+			name:     "test case 3",
+			a:        0,
+			b:        ^uint64(0),
+			expected: 64,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if result := hammingDistance(testCase.a, testCase.b); result != testCase.expected {
+				t.Errorf("%s failed, %d != %d", testCase.name, result, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestMatchFingerprint(t *testing.T) {
+	article := "the quick brown fox jumps over the lazy dog while the farmer watches from the porch and drinks his morning coffee slowly before heading out to the fields for another long day of work under the hot summer sun"
+	template := article + " copyright 2025"
+	unrelated := "golang channels let goroutines communicate by passing values instead of sharing memory which avoids many of the locking bugs found in programs written with threads and mutexes alone across large codebases"
+
+	crawler := &crawlerConfig{
+		mu: &sync.Mutex{},
+		dedup: dedupConfig{
+			mode:     dedupLink,
+			distance: 3,
+		},
+	}
+
+	testCases := []struct {
+		name              string
+		url               string
+		content           string
+		expectedCanonical string
+		expectedDuplicate bool
+	}{
+		{
+			name:              "test case 1",
+			url:               "example.com/article",
+			content:           article,
+			expectedCanonical: "",
+			expectedDuplicate: false,
+		},
+		{
+			name:              "test case 2",
+			url:               "example.com/article?ref=home",
+			content:           template,
+			expectedCanonical: "example.com/article",
+			expectedDuplicate: true,
+		},
+		{
+			name:              "test case 3",
+			url:               "example.com/golang",
+			content:           unrelated,
+			expectedCanonical: "",
+			expectedDuplicate: false,
+		},
+		{
+			name:              "test case 4",
+			url:               "example.com/article",
+			content:           article,
+			expectedCanonical: "",
+			expectedDuplicate: false,
+		},
+		{
+			name:              "test case 5",
+			url:               "example.com/short",
+			content:           "hello world",
+			expectedCanonical: "",
+			expectedDuplicate: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			canonical, _, duplicate := crawler.matchFingerprint(testCase.url, simhash(testCase.content), len(strings.Fields(testCase.content)))
+			if canonical != testCase.expectedCanonical || duplicate != testCase.expectedDuplicate {
+				t.Errorf("%s failed, (%s, %v) != (%s, %v)", testCase.name, canonical, duplicate, testCase.expectedCanonical, testCase.expectedDuplicate)
+			}
+		})
+	}
+}
+
+func TestLoadFingerprints(t *testing.T) {
+	stored := map[string][][]driver.Value{ // LIKE pattern to the rows earlier crawls stored
+		"https://a.com%": {{"https://a.com/x", int64(1)}, {"https://a.com.evil/y", int64(2)}},
+		"https://b.com%": {{"https://b.com/z", int64(3)}},
+	}
+	db := &fakeDB{
+		mu: &sync.Mutex{},
+		answer: func(name string, args []driver.Value) [][]driver.Value {
+			if name != "ListFingerprints" {
+				return nil
+			}
+			return stored[args[0].(string)]
+		},
+	}
+	crawler := testCrawler(t, db, "https://a.com", crawlOptions{})
+
+	testCases := []struct {
+		name     string
+		url      string
+		expected []fingerprint
+	}{
+		{
+			name:     "test case 1",
+			url:      "https://a.com/new",
+			expected: []fingerprint{{url: "https://a.com/x", hash: 1}},
+		},
+		{
+			name:     "test case 2",
+			url:      "https://a.com/other",
+			expected: []fingerprint{{url: "https://a.com/x", hash: 1}},
+		},
+		{
+			name:     "test case 3",
+			url:      "https://b.com/new",
+			expected: []fingerprint{{url: "https://a.com/x", hash: 1}, {url: "https://b.com/z", hash: 3}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			crawler.loadFingerprints(context.Background(), testCase.url)
+			if !reflect.DeepEqual(crawler.fingerprints, testCase.expected) {
+				t.Errorf("%s failed, %v != %v", testCase.name, crawler.fingerprints, testCase.expected)
+			}
+		})
+	}
+}
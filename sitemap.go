@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	maxSitemapSize    = 50 * 1024 * 1024 // limit from the sitemaps protocol, applies after decompression
+	maxSitemapFetches = 50
+	sitemapTimeout    = 30 * time.Second
+)
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+type sitemapDoc struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+func parseSitemap(body []byte) ([]string, []string, error) { // returns page urls and nested sitemap urls
+	if len(body) >= 2 && body[0] == 0x1f && body[1] == 0x8b {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, nil, err
+		}
+		defer reader.Close()
+
+		unzipped, err := io.ReadAll(io.LimitReader(reader, maxSitemapSize))
+		if err != nil {
+			return nil, nil, err
+		}
+		body = unzipped
+	}
+
+	doc := sitemapDoc{}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	pages := []string{}
+	for _, loc := range doc.URLs {
+		if clean := strings.TrimSpace(loc.Loc); clean != "" {
+			pages = append(pages, clean)
+		}
+	}
+	nested := []string{}
+	for _, loc := range doc.Sitemaps {
+		if clean := strings.TrimSpace(loc.Loc); clean != "" {
+			nested = append(nested, clean)
+		}
+	}
+
+	switch doc.XMLName.Local {
+	case "urlset", "sitemapindex":
+		return pages, nested, nil
+	default:
+		return nil, nil, errors.New("not a sitemap")
+	}
+}
+
+func getSitemap(ctx context.Context, f fetcher, rawUrl string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, sitemapTimeout)
+	defer cancel()
+
+	res, _, err := followRedirects(ctx, f, rawUrl, nil, redirectConfig{limit: robotsRedirects}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || 300 <= res.StatusCode {
+		return nil, errors.New("sitemap not available")
+	}
+	return io.ReadAll(io.LimitReader(res.Body, maxSitemapSize))
+}
+
+func discoverSitemaps(domain *url.URL, rules robotsRules) []string {
+	if len(rules.sitemaps) != 0 {
+		return rules.sitemaps
+	}
+	return []string{(&url.URL{
+		Scheme: domain.Scheme,
+		Host:   domain.Host,
+		Path:   "/sitemap.xml",
+	}).String()}
+}
+
+func (c *crawlerConfig) sitemapSeeds(ctx context.Context) []string {
+	rules, err := c.robots.rulesFor(ctx, c.domain)
+	if err != nil {
+		return nil
+	}
+	queue := discoverSitemaps(c.domain, rules)
+	fetched := make(map[string]struct{})
+	seen := make(map[string]struct{})
+
+	seeds := []string{}
+	for ctx.Err() == nil && len(queue) != 0 && len(fetched) < maxSitemapFetches && len(seeds) < c.maxVisits {
+		rawSitemap := queue[0]
+		queue = queue[1:]
+		if _, ok := fetched[rawSitemap]; ok {
+			continue
+		}
+		fetched[rawSitemap] = struct{}{}
+
+		body, err := getSitemap(ctx, c.fetcher, rawSitemap)
+		if err != nil {
+			continue
+		}
+		pages, nested, err := parseSitemap(body)
+		if err != nil {
+			log.Printf("skipping sitemap %s: %v", rawSitemap, err)
+			continue
+		}
+		queue = append(queue, nested...) // sitemap index files point to more sitemaps
+
+		for _, page := range pages {
+			normPage, err := c.canon.canonicalize(page)
+			if err != nil || !c.scope.check(normPage).inScope {
+				continue
+			}
+			if _, ok := seen[page]; ok {
+				continue
+			}
+			seen[page] = struct{}{}
+			seeds = append(seeds, page)
+			if len(seeds) >= c.maxVisits {
+				break
+			}
+		}
+	}
+	return seeds
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+)
+
+func TestParseSitemap(t *testing.T) {
+	urlset := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>https://example.com/</loc></url>
+	<url><loc> https://example.com/about </loc><lastmod>2025-01-01</lastmod></url>
+</urlset>`
+	index := `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>https://example.com/sitemap-1.xml.gz</loc></sitemap>
+	<sitemap><loc>https://example.com/sitemap-2.xml</loc></sitemap>
+</sitemapindex>`
+
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+	if _, err := writer.Write([]byte(urlset)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name           string
+		input          []byte
+		expectedPages  []string
+		expectedNested []string
+		errorPresent   bool
+	}{
+		{
+			name:           "test case 1",
+			input:          []byte(urlset),
+			expectedPages:  []string{"https://example.com/", "https://example.com/about"},
+			expectedNested: []string{},
+			errorPresent:   false,
+		},
+		{
+			name:           "test case 2",
+			input:          []byte(index),
+			expectedPages:  []string{},
+			expectedNested: []string{"https://example.com/sitemap-1.xml.gz", "https://example.com/sitemap-2.xml"},
+			errorPresent:   false,
+		},
+		{
+			name:           "test case 3",
+			input:          buf.Bytes(),
+			expectedPages:  []string{"https://example.com/", "https://example.com/about"},
+			expectedNested: []string{},
+			errorPresent:   false,
+		},
+		{
+			name:           "test case 4",
+			input:          []byte("<html><body>not found</body></html>"),
+			expectedPages:  nil,
+			expectedNested: nil,
+			errorPresent:   true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pages, nested, err := parseSitemap(testCase.input)
+			if (err != nil) != testCase.errorPresent {
+				t.Errorf("%s failed, expecting err = %v", testCase.name, err)
+			} else if comp := reflect.DeepEqual(pages, testCase.expectedPages); !comp {
+				t.Errorf("%s failed, %v != %v", testCase.name, pages, testCase.expectedPages)
+			} else if comp := reflect.DeepEqual(nested, testCase.expectedNested); !comp {
+				t.Errorf("%s failed, %v != %v", testCase.name, nested, testCase.expectedNested)
+			}
+		})
+	}
+}
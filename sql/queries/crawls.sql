@@ -0,0 +1,24 @@
+-- name: CreateCrawl :exec
+INSERT INTO crawls (id, url, options, state, created_at, updated_at) VALUES (
+	?,
+	?,
+	?,
+	'queued',
+	datetime('now'),
+	datetime('now')
+);
+
+-- name: StartCrawl :exec
+UPDATE crawls SET state = 'running', started_at = datetime('now'), updated_at = datetime('now') WHERE id = ? AND state = 'queued';
+
+-- name: CancelQueuedCrawl :exec
+UPDATE crawls SET state = 'cancelled', finished_at = datetime('now'), updated_at = datetime('now') WHERE id = ? AND state = 'queued';
+
+-- name: UpdateCrawlProgress :exec
+UPDATE crawls SET pages_visited = ?, pages_stored = ?, pages_skipped = ?, errors = ?, updated_at = datetime('now') WHERE id = ?;
+
+-- name: FinishCrawl :exec
+UPDATE crawls SET state = ?, pages_visited = ?, pages_stored = ?, pages_skipped = ?, errors = ?, last_error = ?, finished_at = datetime('now'), updated_at = datetime('now') WHERE id = ?;
+
+-- name: GetCrawl :one
+SELECT * FROM crawls WHERE id = ?;
@@ -1,10 +1,68 @@
--- name: InsertData :exec
-INSERT INTO data (url, content, created_at, updated_at) VALUES (
+-- name: UpsertData :exec
+INSERT INTO data (url, content, depth, etag, last_modified, simhash, canonical_url, charset, title, description, headings, full_content, created_at, updated_at) VALUES (
+	?,
+	?,
+	?,
+	?,
+	?,
+	?,
+	?,
+	?,
+	?,
+	?,
 	?,
 	?,
 	datetime('now'),
 	datetime('now')
-);
+) ON CONFLICT (url) DO UPDATE SET
+	content = excluded.content,
+	depth = excluded.depth,
+	etag = excluded.etag,
+	last_modified = excluded.last_modified,
+	simhash = excluded.simhash,
+	canonical_url = excluded.canonical_url,
+	charset = excluded.charset,
+	title = excluded.title,
+	description = excluded.description,
+	headings = excluded.headings,
+	full_content = excluded.full_content,
+	updated_at = datetime('now');
+
+-- name: DeleteData :exec
+DELETE FROM data WHERE url = ?;
 
 -- name: RetrieveData :one
-SELECT url, content FROM data WHERE url=?;
+SELECT url, content, full_content, title, description, headings FROM data WHERE url=?;
+
+-- name: ListDataUrls :many
+SELECT url FROM data ORDER BY url;
+
+-- name: RenameData :exec
+UPDATE data SET url = sqlc.arg(new_url), updated_at = datetime('now') WHERE url = sqlc.arg(old_url);
+
+-- name: GetValidators :one
+SELECT etag, last_modified FROM data WHERE url = ?;
+
+-- name: ListFingerprints :many
+SELECT url, simhash FROM data WHERE url LIKE ? ESCAPE '\' AND simhash IS NOT NULL AND canonical_url IS NULL;
+
+-- name: TouchData :exec
+UPDATE data SET updated_at = datetime('now') WHERE url = ?;
+
+-- name: ListDomainPages :many
+SELECT url FROM data WHERE url = sqlc.arg(root) OR url LIKE sqlc.arg(path_prefix) ESCAPE '\' OR url LIKE sqlc.arg(query_prefix) ESCAPE '\';
+
+-- name: UpdatePageRank :exec
+UPDATE data SET pagerank = ? WHERE url = ?;
+
+-- name: ListPagesByUrl :many
+SELECT url, title, depth, pagerank, updated_at FROM data
+WHERE url = sqlc.arg(root) OR url LIKE sqlc.arg(path_prefix) ESCAPE '\' OR url LIKE sqlc.arg(query_prefix) ESCAPE '\'
+ORDER BY url
+LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);
+
+-- name: ListPagesByPageRank :many
+SELECT url, title, depth, pagerank, updated_at FROM data
+WHERE url = sqlc.arg(root) OR url LIKE sqlc.arg(path_prefix) ESCAPE '\' OR url LIKE sqlc.arg(query_prefix) ESCAPE '\'
+ORDER BY pagerank DESC, url
+LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);
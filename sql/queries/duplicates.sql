@@ -0,0 +1,13 @@
+-- name: InsertDuplicate :exec
+INSERT INTO duplicates (crawl_id, url, canonical_url, distance, created_at) VALUES (
+	?,
+	?,
+	?,
+	?,
+	datetime('now')
+) ON CONFLICT (crawl_id, url) DO UPDATE SET
+	canonical_url = excluded.canonical_url,
+	distance = excluded.distance;
+
+-- name: ListDuplicates :many
+SELECT url, canonical_url, distance FROM duplicates WHERE crawl_id = ? ORDER BY canonical_url, url;
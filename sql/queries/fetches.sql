@@ -0,0 +1,21 @@
+-- name: InsertFetch :exec
+INSERT INTO fetches (crawl_id, url, status_code, error_class, error, broken, created_at) VALUES (
+	?,
+	?,
+	?,
+	?,
+	?,
+	?,
+	datetime('now')
+) ON CONFLICT (crawl_id, url) DO UPDATE SET
+	status_code = excluded.status_code,
+	error_class = excluded.error_class,
+	error = excluded.error,
+	broken = excluded.broken;
+
+-- name: ListBrokenLinks :many
+SELECT fetches.url, fetches.status_code, fetches.error_class, fetches.error, links.source_url, links.anchor_text
+FROM fetches
+LEFT JOIN links ON links.target_url = fetches.url AND links.crawl_id = fetches.crawl_id
+WHERE fetches.crawl_id = ? AND fetches.broken
+ORDER BY fetches.url, links.source_url;
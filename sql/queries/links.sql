@@ -0,0 +1,24 @@
+-- name: DeleteLinksFrom :exec
+DELETE FROM links WHERE source_url = ?;
+
+-- name: InsertLink :exec
+INSERT INTO links (crawl_id, source_url, target_url, anchor_text, rel, created_at) VALUES (
+	?,
+	?,
+	?,
+	?,
+	?,
+	datetime('now')
+) ON CONFLICT (source_url, target_url, anchor_text, rel) DO NOTHING;
+
+-- name: ListInboundLinks :many
+SELECT source_url, anchor_text, rel, crawl_id FROM links WHERE target_url = ? ORDER BY source_url, id;
+
+-- name: ListOutboundLinks :many
+SELECT target_url, anchor_text, rel, crawl_id FROM links WHERE source_url = ? ORDER BY id;
+
+-- name: ListDomainLinks :many
+SELECT source_url, target_url FROM links WHERE source_url = sqlc.arg(root) OR source_url LIKE sqlc.arg(path_prefix) ESCAPE '\' OR source_url LIKE sqlc.arg(query_prefix) ESCAPE '\';
+
+-- name: UpdateLinksCrawl :exec
+UPDATE links SET crawl_id = ? WHERE source_url = ?;
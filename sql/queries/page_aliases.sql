@@ -0,0 +1,11 @@
+-- name: UpsertPageAlias :exec
+INSERT INTO page_aliases (url, store_url, updated_at) VALUES (
+	?,
+	?,
+	datetime('now')
+) ON CONFLICT (url) DO UPDATE SET
+	store_url = excluded.store_url,
+	updated_at = datetime('now');
+
+-- name: GetPageAlias :one
+SELECT store_url FROM page_aliases WHERE url = ?;
@@ -0,0 +1,16 @@
+-- name: InsertRedirect :exec
+INSERT INTO redirects (crawl_id, url, hop, from_url, to_url, status_code, created_at) VALUES (
+	?,
+	?,
+	?,
+	?,
+	?,
+	?,
+	datetime('now')
+) ON CONFLICT (crawl_id, url, hop) DO UPDATE SET
+	from_url = excluded.from_url,
+	to_url = excluded.to_url,
+	status_code = excluded.status_code;
+
+-- name: ListRedirects :many
+SELECT url, hop, from_url, to_url, status_code FROM redirects WHERE crawl_id = ? ORDER BY url, hop;
@@ -0,0 +1,13 @@
+-- name: InsertScopeDecision :exec
+INSERT INTO scope_decisions (crawl_id, url, in_scope, reason, created_at) VALUES (
+	?,
+	?,
+	?,
+	?,
+	datetime('now')
+) ON CONFLICT (crawl_id, url) DO NOTHING;
+
+-- name: ListScopeDecisions :many
+SELECT url, in_scope, reason FROM scope_decisions
+WHERE crawl_id = ?
+ORDER BY url;
@@ -0,0 +1,12 @@
+-- name: InsertSkippedUrl :exec
+INSERT INTO skipped_urls (crawl_id, url, reason, created_at) VALUES (
+	?,
+	?,
+	?,
+	datetime('now')
+) ON CONFLICT (crawl_id, url) DO NOTHING;
+
+-- name: ListSkippedUrls :many
+SELECT url, reason FROM skipped_urls
+WHERE crawl_id = ?
+ORDER BY url;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE crawls (
+	id TEXT PRIMARY KEY,
+	url TEXT NOT NULL,
+	state TEXT NOT NULL,
+	pages_visited INTEGER NOT NULL DEFAULT 0,
+	pages_stored INTEGER NOT NULL DEFAULT 0,
+	pages_skipped INTEGER NOT NULL DEFAULT 0,
+	errors INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT,
+	created_at DATETIME NOT NULL,
+	started_at DATETIME,
+	finished_at DATETIME,
+	updated_at DATETIME NOT NULL
+);
+
+-- +goose Down
+DROP TABLE crawls;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE crawls ADD COLUMN options TEXT NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE crawls DROP COLUMN options;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE data ADD COLUMN depth INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE data DROP COLUMN depth;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE data ADD COLUMN etag TEXT;
+ALTER TABLE data ADD COLUMN last_modified TEXT;
+
+-- +goose Down
+ALTER TABLE data DROP COLUMN last_modified;
+ALTER TABLE data DROP COLUMN etag;
@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE data ADD COLUMN simhash INTEGER;
+ALTER TABLE data ADD COLUMN canonical_url TEXT;
+
+CREATE TABLE duplicates (
+	id INTEGER PRIMARY KEY,
+	crawl_id TEXT NOT NULL REFERENCES crawls (id) ON DELETE CASCADE,
+	url TEXT NOT NULL,
+	canonical_url TEXT NOT NULL,
+	distance INTEGER NOT NULL,
+	created_at DATETIME NOT NULL,
+	UNIQUE (crawl_id, url)
+);
+
+-- +goose Down
+DROP TABLE duplicates;
+ALTER TABLE data DROP COLUMN canonical_url;
+ALTER TABLE data DROP COLUMN simhash;
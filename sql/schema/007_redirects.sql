@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE redirects (
+	id INTEGER PRIMARY KEY,
+	crawl_id TEXT NOT NULL REFERENCES crawls (id) ON DELETE CASCADE,
+	url TEXT NOT NULL,
+	hop INTEGER NOT NULL,
+	from_url TEXT NOT NULL,
+	to_url TEXT NOT NULL,
+	status_code INTEGER NOT NULL,
+	created_at DATETIME NOT NULL,
+	UNIQUE (crawl_id, url, hop)
+);
+
+-- +goose Down
+DROP TABLE redirects;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE data ADD COLUMN charset TEXT;
+
+-- +goose Down
+ALTER TABLE data DROP COLUMN charset;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE links (
+	id INTEGER PRIMARY KEY,
+	crawl_id TEXT NOT NULL REFERENCES crawls (id) ON DELETE CASCADE,
+	source_url TEXT NOT NULL,
+	target_url TEXT NOT NULL,
+	anchor_text TEXT NOT NULL,
+	rel TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	UNIQUE (source_url, target_url, anchor_text, rel)
+);
+
+CREATE INDEX links_target_url ON links (target_url);
+
+-- +goose Down
+DROP INDEX links_target_url;
+DROP TABLE links;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE data ADD COLUMN pagerank REAL;
+
+-- +goose Down
+ALTER TABLE data DROP COLUMN pagerank;
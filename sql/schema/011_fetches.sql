@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE fetches (
+	id INTEGER PRIMARY KEY,
+	crawl_id TEXT NOT NULL REFERENCES crawls (id) ON DELETE CASCADE,
+	url TEXT NOT NULL,
+	status_code INTEGER,
+	error_class TEXT NOT NULL,
+	error TEXT,
+	broken BOOLEAN NOT NULL,
+	created_at DATETIME NOT NULL,
+	UNIQUE (crawl_id, url)
+);
+
+-- +goose Down
+DROP TABLE fetches;
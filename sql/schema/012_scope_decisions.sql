@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE scope_decisions (
+	id INTEGER PRIMARY KEY,
+	crawl_id TEXT NOT NULL REFERENCES crawls (id) ON DELETE CASCADE,
+	url TEXT NOT NULL,
+	in_scope BOOLEAN NOT NULL,
+	reason TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	UNIQUE (crawl_id, url)
+);
+
+-- +goose Down
+DROP TABLE scope_decisions;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE data ADD COLUMN title TEXT;
+ALTER TABLE data ADD COLUMN description TEXT;
+ALTER TABLE data ADD COLUMN headings TEXT;
+
+-- +goose Down
+ALTER TABLE data DROP COLUMN headings;
+ALTER TABLE data DROP COLUMN description;
+ALTER TABLE data DROP COLUMN title;
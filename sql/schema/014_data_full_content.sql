@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE data ADD COLUMN full_content TEXT;
+
+-- +goose Down
+ALTER TABLE data DROP COLUMN full_content;
@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE page_aliases (
+	url TEXT PRIMARY KEY,
+	store_url TEXT NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+-- +goose Down
+DROP TABLE page_aliases;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE skipped_urls (
+	id INTEGER PRIMARY KEY,
+	crawl_id TEXT NOT NULL REFERENCES crawls (id) ON DELETE CASCADE,
+	url TEXT NOT NULL,
+	reason TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	UNIQUE (crawl_id, url)
+);
+
+-- +goose Down
+DROP TABLE skipped_urls;
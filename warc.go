@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const warcVersion = "WARC/1.1"
+
+type warcConfig struct {
+	dir      string // empty disables warc output
+	maxBytes int64  // compressed size at which a crawl moves on to its next file
+}
+
+type warcHeader struct {
+	name  string
+	value string
+}
+
+type warcRecord struct {
+	header textproto.MIMEHeader
+	block  []byte
+}
+
+type warcWriter struct { // one per crawl job, records are gzipped one per member so readers can seek between them
+	mu     *sync.Mutex
+	config warcConfig
+	prefix string
+	seq    int
+	file   *os.File
+	size   int64
+}
+
+type warcFetcher struct { // archives every round trip of a crawl, including redirects and error pages
+	next     fetcher
+	writer   *warcWriter
+	maxBytes int64 // bodies are archived up to the crawler's body limit, 0 archives them whole
+}
+
+func (c *crawlerConfig) archiveTo(writer *warcWriter) { // robots.txt gets a cache of its own so every crawl archives the rules it followed
+	c.fetcher = &warcFetcher{
+		next:     c.fetcher,
+		writer:   writer,
+		maxBytes: c.body.maxBytes,
+	}
+	c.robots = &robotsCache{
+		mu:      &sync.Mutex{},
+		entries: make(map[string]*robotsEntry),
+		fetcher: c.fetcher,
+		agent:   c.robots.agent,
+	}
+}
+
+func newWarcWriter(config warcConfig, prefix string) (*warcWriter, error) {
+	if err := os.MkdirAll(config.dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &warcWriter{
+		mu:     &sync.Mutex{},
+		config: config,
+		prefix: prefix,
+	}, nil
+}
+
+func (w *warcWriter) rotate() error { // callers hold w.mu
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+	}
+	w.seq++
+	name := filepath.Join(w.config.dir, fmt.Sprintf("%s-%05d.warc.gz", w.prefix, w.seq))
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	w.file = file
+	w.size = 0
+
+	info := []byte("software: " + userAgent + "\r\nformat: WARC File Format 1.1\r\n")
+	return w.append([]warcHeader{
+		{"WARC-Type", "warcinfo"},
+		{"WARC-Record-ID", warcRecordID()},
+		{"WARC-Date", warcDate(time.Now())},
+		{"WARC-Filename", filepath.Base(name)},
+		{"Content-Type", "application/warc-fields"},
+	}, info)
+}
+
+func (w *warcWriter) append(headers []warcHeader, block []byte) error { // callers hold w.mu
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	fmt.Fprintf(gz, "%s\r\n", warcVersion)
+	for _, header := range headers {
+		fmt.Fprintf(gz, "%s: %s\r\n", header.name, header.value)
+	}
+	fmt.Fprintf(gz, "Content-Length: %d\r\n\r\n", len(block))
+	gz.Write(block)
+	gz.Write([]byte("\r\n\r\n"))
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	n, err := w.file.Write(buf.Bytes())
+	w.size += int64(n)
+	return err
+}
+
+func (w *warcWriter) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+func (f *warcFetcher) do(req *http.Request) (*http.Response, error) {
+	res, err := f.next.do(req)
+	if err != nil {
+		return nil, err
+	}
+	reader := io.Reader(res.Body)
+	if f.maxBytes > 0 {
+		reader = io.LimitReader(res.Body, f.maxBytes+1) // one extra byte tells the crawler the body was longer
+	}
+	body, err := io.ReadAll(reader)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	archived, truncated := body, false
+	if f.maxBytes > 0 && int64(len(body)) > f.maxBytes {
+		archived, truncated = body[:f.maxBytes], true
+	}
+	if err := f.writer.exchange(req, res, archived, truncated, time.Now()); err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
+
+func (w *warcWriter) exchange(req *http.Request, res *http.Response, body []byte, truncated bool, at time.Time) error {
+	requestBlock := &bytes.Buffer{} // the fetcher has set its headers by now, so this is what was sent
+	sent := req.Clone(req.Context())
+	sent.Body = nil
+	if err := sent.Write(requestBlock); err != nil {
+		return err
+	}
+
+	saved := *res // go has already undone chunking and compression, so the payload is what a browser would see
+	saved.ProtoMajor, saved.ProtoMinor = 1, 1
+	saved.TransferEncoding = nil
+	saved.ContentLength = int64(len(body))
+	saved.Body = io.NopCloser(bytes.NewReader(body))
+	responseBlock := &bytes.Buffer{}
+	if err := saved.Write(responseBlock); err != nil {
+		return err
+	}
+
+	target := req.URL.String()
+	date := warcDate(at)
+	responseID := warcRecordID()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil || (w.config.maxBytes > 0 && w.size >= w.config.maxBytes) {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	headers := []warcHeader{
+		{"WARC-Type", "response"},
+		{"WARC-Record-ID", responseID},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", target},
+		{"Content-Type", "application/http;msgtype=response"},
+		{"WARC-Payload-Digest", warcDigest(body)},
+		{"WARC-Block-Digest", warcDigest(responseBlock.Bytes())},
+	}
+	if truncated {
+		headers = append(headers, warcHeader{"WARC-Truncated", "length"})
+	}
+	if err := w.append(headers, responseBlock.Bytes()); err != nil {
+		return err
+	}
+	return w.append([]warcHeader{
+		{"WARC-Type", "request"},
+		{"WARC-Record-ID", warcRecordID()},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", target},
+		{"WARC-Concurrent-To", responseID},
+		{"Content-Type", "application/http;msgtype=request"},
+		{"WARC-Block-Digest", warcDigest(requestBlock.Bytes())},
+	}, requestBlock.Bytes())
+}
+
+func warcRecordID() string {
+	return "<urn:uuid:" + uuid.NewString() + ">"
+}
+
+func warcDate(at time.Time) string {
+	return at.UTC().Format(time.RFC3339Nano)
+}
+
+func warcDigest(data []byte) string { // sha1 in base32, what existing warc tools expect
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+func readWarcRecord(r *bufio.Reader) (warcRecord, error) { // io.EOF once the file is exhausted
+	version, err := r.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && version == "" {
+			return warcRecord{}, io.EOF
+		}
+		return warcRecord{}, io.ErrUnexpectedEOF
+	}
+	if !strings.HasPrefix(strings.TrimSpace(version), "WARC/") {
+		return warcRecord{}, errors.New("not a warc record")
+	}
+
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return warcRecord{}, err
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return warcRecord{}, errors.New("invalid record length")
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(r, block); err != nil {
+		return warcRecord{}, err
+	}
+	trailer := make([]byte, 4)
+	if _, err := io.ReadFull(r, trailer); err != nil || string(trailer) != "\r\n\r\n" {
+		return warcRecord{}, errors.New("missing record trailer")
+	}
+	return warcRecord{
+		header: header,
+		block:  block,
+	}, nil
+}
+
+func readWarcFile(path string, each func(warcRecord) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file) // reads the per-record members as one stream
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	r := bufio.NewReader(gz)
+	for {
+		record, err := readWarcRecord(r)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := each(record); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var errFound = errors.New("record found") // stops the scan once extract has what it needs
+
+func runWarcCommand(args []string, out io.Writer) error { // rumbling warc list FILE... | rumbling warc extract FILE RECORD-ID
+	if len(args) == 0 {
+		return errors.New("usage: warc list FILE... | warc extract FILE RECORD-ID")
+	}
+
+	switch args[0] {
+	case "list":
+		if len(args) < 2 {
+			return errors.New("usage: warc list FILE...")
+		}
+		for _, path := range args[1:] {
+			if err := readWarcFile(path, func(record warcRecord) error {
+				_, err := fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%d\n",
+					record.header.Get("WARC-Record-ID"),
+					record.header.Get("WARC-Type"),
+					record.header.Get("WARC-Date"),
+					record.header.Get("WARC-Target-URI"),
+					len(record.block),
+				)
+				return err
+			}); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		return nil
+	case "extract":
+		if len(args) != 3 {
+			return errors.New("usage: warc extract FILE RECORD-ID")
+		}
+		err := readWarcFile(args[1], func(record warcRecord) error {
+			if record.header.Get("WARC-Record-ID") != args[2] {
+				return nil
+			}
+			if _, err := out.Write(record.block); err != nil {
+				return err
+			}
+			return errFound
+		})
+		if errors.Is(err, errFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return errors.New("record not found")
+	default:
+		return fmt.Errorf("unknown warc command %q", args[0])
+	}
+}
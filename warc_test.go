@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWarcWriter(t *testing.T) {
+	server := httptest.NewServer(siteHandler(func(path string) []string {
+		if path == "/" {
+			return []string{"/a", "/b", "/c"}
+		}
+		return nil
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name      string
+		maxBytes  int64
+		bodyBytes int64
+		files     int
+		responses int
+		truncated int
+	}{
+		{
+			name:      "test case 1",
+			maxBytes:  0,
+			files:     1,
+			responses: 6,
+		},
+		{
+			name:      "test case 2",
+			maxBytes:  1, // every exchange starts a new file
+			files:     6, // the seed, three pages, robots.txt and sitemap.xml
+			responses: 6,
+		},
+		{
+			name:      "test case 3",
+			bodyBytes: 16, // cuts the seed's links, robots.txt and sitemap.xml are still archived
+			files:     1,
+			responses: 3,
+			truncated: 3,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writer, err := newWarcWriter(warcConfig{dir: dir, maxBytes: testCase.maxBytes}, "job")
+			if err != nil {
+				t.Fatal(err)
+			}
+			db := &fakeDB{mu: &sync.Mutex{}}
+			crawler := testCrawler(t, db, server.URL, crawlOptions{MaxPages: 10, MaxDepth: intPtr(1), Concurrency: 2})
+			crawler.body.maxBytes = testCase.bodyBytes
+			crawler.archiveTo(writer)
+			crawler.initCrawl(context.Background(), server.URL)
+			if err := writer.close(); err != nil {
+				t.Fatal(err)
+			}
+
+			files, err := filepath.Glob(filepath.Join(dir, "job-*.warc.gz"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(files) != testCase.files {
+				t.Errorf("%s failed, %v != %v", testCase.name, len(files), testCase.files)
+			}
+
+			truncated := 0
+			responses := make(map[string]string) // record id to target
+			requests := make(map[string]string)  // concurrent-to to target
+			for _, file := range files {
+				first := true
+				if err := readWarcFile(file, func(record warcRecord) error {
+					kind := record.header.Get("WARC-Type")
+					if first && kind != "warcinfo" {
+						t.Errorf("%s failed, %s starts with a %s record", testCase.name, file, kind)
+					}
+					first = false
+					if digest := warcDigest(record.block); kind != "warcinfo" && digest != record.header.Get("WARC-Block-Digest") {
+						t.Errorf("%s failed, %v != %v", testCase.name, digest, record.header.Get("WARC-Block-Digest"))
+					}
+
+					switch kind {
+					case "response":
+						res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(record.block)), nil)
+						if err != nil {
+							return err
+						}
+						body, err := io.ReadAll(res.Body)
+						if err != nil {
+							return err
+						}
+						if digest := warcDigest(body); digest != record.header.Get("WARC-Payload-Digest") {
+							t.Errorf("%s failed, %v != %v", testCase.name, digest, record.header.Get("WARC-Payload-Digest"))
+						}
+						responses[record.header.Get("WARC-Record-ID")] = record.header.Get("WARC-Target-URI")
+						if record.header.Get("WARC-Truncated") == "length" {
+							truncated++
+						}
+					case "request":
+						if !strings.Contains(string(record.block), "User-Agent: "+userAgent) {
+							t.Errorf("%s failed, request record has no user agent", testCase.name)
+						}
+						requests[record.header.Get("WARC-Concurrent-To")] = record.header.Get("WARC-Target-URI")
+					}
+					return nil
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if len(responses) != testCase.responses || truncated != testCase.truncated {
+				t.Errorf("%s failed, %v %v != %v %v", testCase.name, len(responses), truncated, testCase.responses, testCase.truncated)
+			}
+			for id, target := range responses {
+				if requests[id] != target {
+					t.Errorf("%s failed, %v != %v", testCase.name, requests[id], target)
+				}
+			}
+		})
+	}
+}
+
+func TestWarcCommand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<p>evidence</p>")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writer, err := newWarcWriter(warcConfig{dir: dir}, "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &warcFetcher{
+		next:   newHTTPFetcher(fetcherConfig{userAgent: userAgent}),
+		writer: writer,
+	}
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/page", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := f.do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	writer.close()
+	file := filepath.Join(dir, "job-00001.warc.gz")
+
+	list := &bytes.Buffer{}
+	if err := runWarcCommand([]string{"list", file}, list); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(list.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("list failed, %v != %v", len(lines), 3)
+	}
+	fields := strings.Split(lines[1], "\t")
+	if fields[1] != "response" || fields[3] != server.URL+"/page" {
+		t.Errorf("list failed, %v", lines[1])
+	}
+
+	extracted := &bytes.Buffer{}
+	if err := runWarcCommand([]string{"extract", file, fields[0]}, extracted); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(extracted.String(), "HTTP/1.1 200 OK\r\n") || !strings.HasSuffix(extracted.String(), "<p>evidence</p>") {
+		t.Errorf("extract failed, %q", extracted.String())
+	}
+	if err := runWarcCommand([]string{"extract", file, "<urn:uuid:missing>"}, io.Discard); err == nil {
+		t.Errorf("extract failed, expected an error for a missing record")
+	}
+}